@@ -0,0 +1,45 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command tmplfmt formats text and html template source files.
+//
+// Usage:
+//
+//	tmplfmt [flags] [path ...]
+//
+// Without any paths, tmplfmt formats the standard input. Directories are
+// walked recursively, formatting files matching the -include globs and not
+// matching the -exclude globs. Files given explicitly are always formatted.
+// By default the formatted source is written to the standard output.
+//
+// The flags are:
+//
+//	-l          list files whose formatting differs from tmplfmt's
+//	-w          write the result to the source file instead of stdout
+//	-d          print unified diffs instead of the formatted source
+//	-nest-pad s pad nested actions with s inside the open delimiter
+//	-compact    omit the padding spaces within the action delimiters
+//	-left s     the left action delimiter (default "{{")
+//	-right s    the right action delimiter (default "}}")
+//	-include g  glob of directory entries to format (repeatable)
+//	-exclude g  glob of paths to skip (repeatable)
+package main
+
+import (
+	"os"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-corelibs/tmplstr"
+	"github.com/go-corelibs/tmplstr/internal/diff"
+	"github.com/go-corelibs/tmplstr/internal/fsutil"
+)
+
+// DefaultIncludes are the -include globs used when none are given
+var DefaultIncludes = []string{"*.tmpl", "*.tpl", "*.gotmpl", "*.gohtml", "*.html"}
+
+const stdinName = "<standard input>"
+
+type globs []string
+
+func (g *globs) String() string {
+	return strings.Join(*g, ",")
+}
+
+func (g *globs) Set(value string) error {
+	if _, err := filepath.Match(value, ""); err != nil {
+		return fmt.Errorf("%w: %s", err, value)
+	}
+	*g = append(*g, value)
+	return nil
+}
+
+type formatter struct {
+	list, write, diff bool
+	opts              tmplstr.FormatOptions
+	parser            *tmplstr.Parser
+	includes          globs
+	excludes          globs
+	stdout, stderr    io.Writer
+	failed            bool
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) (code int) {
+	f := &formatter{stdout: stdout, stderr: stderr}
+	var left, right string
+
+	flags := flag.NewFlagSet("tmplfmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.BoolVar(&f.list, "l", false, "list files whose formatting differs from tmplfmt's")
+	flags.BoolVar(&f.write, "w", false, "write result to (source) file instead of stdout")
+	flags.BoolVar(&f.diff, "d", false, "display diffs instead of rewriting files")
+	flags.StringVar(&f.opts.NestPadding, "nest-pad", "", "pad nested actions with this string inside the delimiters")
+	flags.BoolVar(&f.opts.Compact, "compact", false, "omit padding spaces within action delimiters")
+	flags.StringVar(&left, "left", tmplstr.DefaultLeftDelim, "left action delimiter")
+	flags.StringVar(&right, "right", tmplstr.DefaultRightDelim, "right action delimiter")
+	flags.Var(&f.includes, "include", "glob of directory entries to format (repeatable)")
+	flags.Var(&f.excludes, "exclude", "glob of paths to skip (repeatable)")
+	flags.Usage = func() {
+		_, _ = fmt.Fprintf(stderr, "usage: tmplfmt [flags] [path ...]\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if len(f.includes) == 0 {
+		f.includes = DefaultIncludes
+	}
+
+	var err error
+	if f.parser, err = tmplstr.NewParser(tmplstr.WithDelims(left, right)); err != nil {
+		_, _ = fmt.Fprintf(stderr, "tmplfmt: %v\n", err)
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		if f.write {
+			_, _ = fmt.Fprintf(stderr, "tmplfmt: cannot use -w with standard input\n")
+			return 2
+		}
+		var data []byte
+		if data, err = io.ReadAll(stdin); err != nil {
+			f.errorf("%s: %v", stdinName, err)
+		} else {
+			f.process(stdinName, data, 0)
+		}
+	}
+
+	for _, arg := range flags.Args() {
+		var info fs.FileInfo
+		if info, err = os.Stat(arg); err != nil {
+			f.errorf("%v", err)
+		} else if info.IsDir() {
+			f.walk(arg)
+		} else {
+			f.file(arg, info)
+		}
+	}
+
+	if f.failed {
+		return 2
+	}
+	return 0
+}
+
+func (f *formatter) errorf(format string, argv ...any) {
+	f.failed = true
+	_, _ = fmt.Fprintf(f.stderr, format+"\n", argv...)
+}
+
+// walk formats all included files within the given directory
+func (f *formatter) walk(root string) {
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			f.errorf("%v", err)
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		if rel != "." && matchAny(f.excludes, path, rel, d.Name()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !matchAny(f.includes, d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			f.errorf("%v", err)
+			return nil
+		}
+		f.file(path, info)
+		return nil
+	})
+	if err != nil {
+		f.errorf("%v", err)
+	}
+}
+
+// matchAny reports whether any of the given names match any of the globs
+func matchAny(patterns []string, names ...string) bool {
+	for _, pattern := range patterns {
+		for _, name := range names {
+			if matched, _ := filepath.Match(pattern, name); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// file formats the given file
+func (f *formatter) file(path string, info fs.FileInfo) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		f.errorf("%v", err)
+		return
+	}
+	f.process(path, data, info.Mode().Perm())
+}
+
+// process formats the given source, reporting the result according to the
+// -l, -w and -d flags
+func (f *formatter) process(name string, data []byte, perm fs.FileMode) {
+	source := string(data)
+	tree, err := f.parser.ParseTemplate(name, source)
+	if err != nil {
+		f.errorf("%v", err)
+		return
+	}
+	formatted := tmplstr.Format(tree, f.opts)
+	changed := formatted != source
+
+	if f.list && changed {
+		_, _ = fmt.Fprintln(f.stdout, name)
+	}
+	if f.write && changed {
+		if err = fsutil.WriteFile(name, []byte(formatted), perm); err != nil {
+			f.errorf("%v", err)
+			return
+		}
+	}
+	if f.diff && changed {
+		_, _ = fmt.Fprintf(f.stdout, "diff %s.orig %s\n", name, name)
+		_, _ = io.WriteString(f.stdout, diff.Unified(name+".orig", name, source, formatted))
+	}
+	if !f.list && !f.write && !f.diff {
+		_, _ = io.WriteString(f.stdout, formatted)
+	}
+}
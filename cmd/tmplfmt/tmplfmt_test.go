@@ -0,0 +1,169 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func tRun(stdin string, args ...string) (code int, stdout, stderr string) {
+	var o, e bytes.Buffer
+	code = run(args, strings.NewReader(stdin), &o, &e)
+	return code, o.String(), e.String()
+}
+
+func tWriteFiles(dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		So(os.MkdirAll(filepath.Dir(path), 0o755), ShouldBeNil)
+		So(os.WriteFile(path, []byte(content), 0o640), ShouldBeNil)
+	}
+}
+
+func tReadFile(path string) string {
+	data, err := os.ReadFile(path)
+	So(err, ShouldBeNil)
+	return string(data)
+}
+
+func TestTmplFmt(t *testing.T) {
+	const unformatted = "{{if .x}}{{.y|upper}}{{end}}\n"
+	const formatted = "{{ if .x }}{{ .y | upper }}{{ end }}\n"
+
+	Convey("stdin", t, func() {
+		code, stdout, stderr := tRun(unformatted)
+		So(code, ShouldEqual, 0)
+		So(stderr, ShouldEqual, "")
+		So(stdout, ShouldEqual, formatted)
+
+		code, stdout, _ = tRun(unformatted, "-l")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, "<standard input>\n")
+
+		code, stdout, _ = tRun(formatted, "-l")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, "")
+
+		code, _, stderr = tRun(unformatted, "-w")
+		So(code, ShouldEqual, 2)
+		So(stderr, ShouldContainSubstring, "cannot use -w with standard input")
+	})
+
+	Convey("options", t, func() {
+		_, stdout, _ := tRun(unformatted, "-compact")
+		So(stdout, ShouldEqual, "{{if .x}}{{.y | upper}}{{end}}\n")
+
+		_, stdout, _ = tRun(unformatted, "-nest-pad", "  ")
+		So(stdout, ShouldEqual, "{{ if .x }}{{   .y | upper }}{{ end }}\n")
+
+		_, stdout, _ = tRun("[[if .x]]{{.y}}[[end]]", "-left", "[[", "-right", "]]")
+		So(stdout, ShouldEqual, "[[ if .x ]]{{.y}}[[ end ]]")
+
+		code, _, stderr := tRun(unformatted, "-left", "}}")
+		So(code, ShouldEqual, 2)
+		So(stderr, ShouldStartWith, "tmplfmt: ")
+
+		code, _, _ = tRun(unformatted, "-nope")
+		So(code, ShouldEqual, 2)
+
+		code, _, stderr = tRun(unformatted, "-include", "[")
+		So(code, ShouldEqual, 2)
+		So(stderr, ShouldContainSubstring, "syntax error in pattern")
+	})
+
+	Convey("parse errors", t, func() {
+		code, stdout, stderr := tRun("{{ if ( }}")
+		So(code, ShouldEqual, 2)
+		So(stdout, ShouldEqual, "")
+		So(stderr, ShouldStartWith, "<standard input>:1:")
+	})
+
+	Convey("diff", t, func() {
+		code, stdout, _ := tRun(unformatted, "-d")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, `diff <standard input>.orig <standard input>
+--- <standard input>.orig
++++ <standard input>
+@@ -1 +1 @@
+-{{if .x}}{{.y|upper}}{{end}}
++{{ if .x }}{{ .y | upper }}{{ end }}
+`)
+	})
+
+	Convey("directories", t, func() {
+		dir := t.TempDir()
+		tWriteFiles(dir, map[string]string{
+			"page.tmpl":              unformatted,
+			"clean.tmpl":             formatted,
+			"notes.txt":              unformatted,
+			"partials/nav.gohtml":    unformatted,
+			"vendor/lib/other.tmpl":  unformatted,
+			"partials/skip.min.tmpl": unformatted,
+		})
+		page := filepath.Join(dir, "page.tmpl")
+		nav := filepath.Join(dir, "partials", "nav.gohtml")
+
+		code, stdout, stderr := tRun("", "-l", "-exclude", "vendor", "-exclude", "*.min.tmpl", dir)
+		So(code, ShouldEqual, 0)
+		So(stderr, ShouldEqual, "")
+		So(stdout, ShouldEqual, page+"\n"+nav+"\n")
+
+		code, stdout, _ = tRun("", "-l", "-include", "*.txt", dir)
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, filepath.Join(dir, "notes.txt")+"\n")
+
+		Convey("explicit files", func() {
+			notes := filepath.Join(dir, "notes.txt")
+			code, stdout, _ = tRun("", "-l", notes, filepath.Join(dir, "clean.tmpl"))
+			So(code, ShouldEqual, 0)
+			So(stdout, ShouldEqual, notes+"\n")
+
+			code, _, stderr = tRun("", filepath.Join(dir, "missing.tmpl"))
+			So(code, ShouldEqual, 2)
+			So(stderr, ShouldContainSubstring, "missing.tmpl")
+		})
+
+		Convey("write", func() {
+			code, stdout, _ = tRun("", "-w", "-l", "-exclude", "vendor", dir)
+			So(code, ShouldEqual, 0)
+			So(stdout, ShouldContainSubstring, page)
+			So(tReadFile(page), ShouldEqual, formatted)
+			So(tReadFile(nav), ShouldEqual, formatted)
+			So(tReadFile(filepath.Join(dir, "notes.txt")), ShouldEqual, unformatted)
+			So(tReadFile(filepath.Join(dir, "vendor", "lib", "other.tmpl")), ShouldEqual, unformatted)
+			info, err := os.Stat(page)
+			So(err, ShouldBeNil)
+			So(info.Mode().Perm(), ShouldEqual, os.FileMode(0o640))
+
+			code, stdout, _ = tRun("", "-l", "-exclude", "vendor", dir)
+			So(code, ShouldEqual, 0)
+			So(stdout, ShouldEqual, "")
+		})
+
+		Convey("parse errors continue", func() {
+			tWriteFiles(dir, map[string]string{"broken.tmpl": "{{ if ( }}"})
+			code, stdout, stderr = tRun("", "-l", "-exclude", "vendor", "-exclude", "partials", dir)
+			So(code, ShouldEqual, 2)
+			So(stdout, ShouldEqual, page+"\n")
+			So(stderr, ShouldContainSubstring, "broken.tmpl:1:")
+		})
+	})
+}
@@ -0,0 +1,335 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-corelibs/tmplstr"
+	"github.com/go-corelibs/tmplstr/internal/diff"
+	"github.com/go-corelibs/tmplstr/internal/fsutil"
+	"github.com/go-corelibs/tmplstr/lint"
+)
+
+var cmdAST = &command{
+	name:  "ast",
+	usage: "print the JSON abstract syntax tree of each file",
+	setup: func(c *context) func(inputs []*input) error {
+		return func(inputs []*input) (err error) {
+			if len(inputs) == 1 {
+				c.printf("%s\n", inputs[0].tree.Format())
+				return
+			}
+			trees := make(map[string]tmplstr.Tree, len(inputs))
+			for _, in := range inputs {
+				trees[in.name] = in.tree
+			}
+			return c.printJSON(trees)
+		}
+	},
+}
+
+var cmdStripComments = &command{
+	name:  "strip-comments",
+	usage: "remove inline comments from within template actions",
+	raw:   true,
+	setup: func(c *context) func(inputs []*input) error {
+		var prune, write bool
+		c.flags.BoolVar(&prune, "prune", false, "use PruneTemplateComments instead of RemoveTemplateComments")
+		c.flags.BoolVar(&write, "w", false, "write the result to the source file instead of stdout")
+		return func(inputs []*input) (err error) {
+			type stripped struct {
+				File   string `json:"file"`
+				Source string `json:"source"`
+			}
+			var results []stripped
+			var errs []error
+			if write {
+				// reject standard input before any file is rewritten
+				for _, in := range inputs {
+					if in.name == stdinName {
+						errs = append(errs, errors.New("cannot use -w with standard input"))
+					}
+				}
+				if len(errs) > 0 {
+					return errors.Join(errs...)
+				}
+			}
+			for _, in := range inputs {
+				var source string
+				if prune {
+					if source, err = c.parser.PruneTemplateComments(in.source); err != nil {
+						var pe *tmplstr.ParseError
+						if errors.As(err, &pe) {
+							pe.Pos.Filename = in.name
+						}
+						errs = append(errs, err)
+						continue
+					}
+				} else {
+					source = c.parser.RemoveTemplateComments(in.source)
+				}
+				switch {
+				case write:
+					if source != in.source {
+						if err = fsutil.WriteFile(in.name, []byte(source), in.perm); err != nil {
+							errs = append(errs, err)
+						}
+					}
+				case c.json:
+					results = append(results, stripped{File: in.name, Source: source})
+				default:
+					c.printf("%s", source)
+				}
+			}
+			if c.json && !write {
+				if err = c.printJSON(results); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			return errors.Join(errs...)
+		}
+	},
+}
+
+var cmdFuncs = &command{
+	name:  "funcs",
+	usage: "list the function calls made within template actions",
+	setup: func(c *context) func(inputs []*input) error {
+		var unique bool
+		c.flags.BoolVar(&unique, "u", false, "list each function name only once")
+		return func(inputs []*input) (err error) {
+			calls := []tmplstr.FuncCall{}
+			seen := make(map[string]bool)
+			for _, in := range inputs {
+				for _, call := range tmplstr.FuncCalls(in.tree) {
+					if unique {
+						if seen[call.Name] {
+							continue
+						}
+						seen[call.Name] = true
+					}
+					calls = append(calls, call)
+				}
+			}
+			if c.json {
+				return c.printJSON(calls)
+			}
+			for _, call := range calls {
+				if unique {
+					c.printf("%s\n", call.Name)
+					continue
+				}
+				c.printf("%v: %s (%s)\n", call.Pos, call.Name, plural(call.NumArgs(), "arg", "args"))
+			}
+			return
+		}
+	},
+}
+
+var cmdVars = &command{
+	name:  "vars",
+	usage: "list the variable declarations and scope problems",
+	setup: func(c *context) func(inputs []*input) error {
+		return func(inputs []*input) (err error) {
+			merged := &tmplstr.ScopeAnalysis{}
+			var errs []error
+			for _, in := range inputs {
+				var analysis *tmplstr.ScopeAnalysis
+				if analysis, err = tmplstr.AnalyzeScopes(in.tree); err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				merged.Decls = append(merged.Decls, analysis.Decls...)
+				merged.Uses = append(merged.Uses, analysis.Uses...)
+				merged.Diagnostics = append(merged.Diagnostics, analysis.Diagnostics...)
+			}
+			if c.json {
+				if err = c.printJSON(merged); err != nil {
+					errs = append(errs, err)
+				}
+				return errors.Join(errs...)
+			}
+			for _, decl := range merged.Decls {
+				c.printf("%v: %s (%s)\n", decl.Pos, decl.Name, plural(len(decl.Uses), "use", "uses"))
+			}
+			for _, d := range merged.Diagnostics {
+				c.printf("%s\n", d.String())
+			}
+			return errors.Join(errs...)
+		}
+	},
+}
+
+var cmdDefines = &command{
+	name:  "defines",
+	usage: "list the define, block and template actions",
+	setup: func(c *context) func(inputs []*input) error {
+		var dot bool
+		c.flags.BoolVar(&dot, "dot", false, "print a Graphviz DOT graph")
+		return func(inputs []*input) (err error) {
+			trees := make([]tmplstr.Tree, len(inputs))
+			for idx, in := range inputs {
+				trees[idx] = in.tree
+			}
+			var graph *tmplstr.DefinitionGraph
+			if graph, err = tmplstr.NewDefinitionGraph(trees...); err != nil {
+				return
+			}
+			switch {
+			case dot:
+				c.printf("%s", graph.DOT())
+			case c.json:
+				return c.printJSON(graph)
+			default:
+				for _, d := range graph.Definitions {
+					keyword := "define"
+					if d.Block {
+						keyword = "block"
+					}
+					c.printf("%v: %s %q\n", d.Pos, keyword, d.Name)
+				}
+				for _, r := range graph.References {
+					if r.Block {
+						continue
+					}
+					c.printf("%v: template %q\n", r.Pos, r.Name)
+				}
+			}
+			return
+		}
+	},
+}
+
+var cmdMessages = &command{
+	name:  "messages",
+	usage: "list the translatable messages",
+	setup: func(c *context) func(inputs []*input) error {
+		var funcs string
+		var pot bool
+		c.flags.StringVar(&funcs, "funcs", strings.Join(tmplstr.DefaultMessageFuncs, ","), "comma separated message functions, plural forms suffixed with :plural")
+		c.flags.BoolVar(&pot, "pot", false, "print a gettext POT catalog")
+		return func(inputs []*input) (err error) {
+			names := strings.Split(funcs, ",")
+			messages := []tmplstr.Message{}
+			for _, in := range inputs {
+				messages = append(messages, tmplstr.ExtractMessages(in.tree, names...)...)
+			}
+			switch {
+			case pot:
+				c.printf("%s", tmplstr.NewPOT(messages).Render())
+			case c.json:
+				return c.printJSON(messages)
+			default:
+				for _, m := range messages {
+					if m.Plural != "" {
+						c.printf("%v: %q %q\n", m.Pos, m.ID, m.Plural)
+					} else {
+						c.printf("%v: %q\n", m.Pos, m.ID)
+					}
+				}
+			}
+			return
+		}
+	},
+}
+
+var cmdLint = &command{
+	name:  "lint",
+	usage: "report, and optionally fix, lint problems",
+	setup: func(c *context) func(inputs []*input) error {
+		var rules, deprecated string
+		var fix, showDiff bool
+		c.flags.StringVar(&rules, "rules", "", "comma separated rules to check, all rules when empty")
+		c.flags.StringVar(&deprecated, "deprecated", "", "comma separated deprecated functions, as name or name=replacement")
+		c.flags.BoolVar(&fix, "fix", false, "apply suggested fixes, rewriting files in place and printing diagnostics to stderr")
+		c.flags.BoolVar(&showDiff, "d", false, "with -fix, print unified diffs instead of rewriting files")
+		return func(inputs []*input) (err error) {
+			fixer := &lint.Fixer{
+				Registry: lint.NewRegistry(lint.BuiltinRules()...),
+				Parser:   c.parser,
+			}
+			if rules != "" {
+				fixer.Rules = strings.Split(rules, ",")
+			}
+			if deprecated != "" {
+				funcs := make(map[string]string)
+				for _, entry := range strings.Split(deprecated, ",") {
+					name, replacement, _ := strings.Cut(entry, "=")
+					funcs[name] = replacement
+				}
+				fixer.Registry.Register(&lint.DeprecatedFuncs{Funcs: funcs})
+			}
+
+			output := c.stdout
+			if fix {
+				output = c.stderr
+			}
+			all := lint.Diagnostics{}
+			var errs []error
+			for _, in := range inputs {
+				var diagnostics lint.Diagnostics
+				if !fix {
+					if diagnostics, err = fixer.Registry.Lint(in.tree, fixer.Rules...); err != nil {
+						errs = append(errs, err)
+						continue
+					}
+				} else {
+					var fixed string
+					if fixed, diagnostics, _, err = fixer.Fix(in.name, in.source); err != nil {
+						errs = append(errs, err)
+						continue
+					}
+					switch {
+					case showDiff:
+						if fixed != in.source {
+							c.printf("diff %s.orig %s\n%s", in.name, in.name, diff.Unified(in.name+".orig", in.name, in.source, fixed))
+						}
+					case in.name == stdinName:
+						c.printf("%s", fixed)
+					case fixed != in.source:
+						if err = fsutil.WriteFile(in.name, []byte(fixed), in.perm); err != nil {
+							errs = append(errs, err)
+						}
+					}
+				}
+				all = append(all, diagnostics...)
+			}
+
+			c.failed = len(all) > 0
+			if c.json {
+				if err = writeJSON(output, all); err != nil {
+					errs = append(errs, err)
+				}
+			} else {
+				for _, d := range all {
+					_, _ = fmt.Fprintln(output, d.String())
+				}
+			}
+			return errors.Join(errs...)
+		}
+	},
+}
+
+// plural returns the count followed by the singular or plural noun
+func plural(count int, singular, plural string) string {
+	if count == 1 {
+		return "1 " + singular
+	}
+	return strconv.Itoa(count) + " " + plural
+}
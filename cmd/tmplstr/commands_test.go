@@ -0,0 +1,277 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const tSource = `{{/* Page title */}}
+{{ define "page" }}{{ $x := .Title /* the title */ }}{{ printf "%s" $x | upper }}{{ _ "Hello" }}{{ template "nav" . }}{{ end }}
+{{ block "footer" . }}{{ __ "one" "many" .N }}{{ $y := 1 }}{{ end }}
+`
+
+func TestCommands(t *testing.T) {
+	Convey("ast", t, func() {
+		code, stdout, _ := tRun(`a{{.b}}`, "ast")
+		So(code, ShouldEqual, 0)
+		var tree []map[string]any
+		So(json.Unmarshal([]byte(stdout), &tree), ShouldBeNil)
+		So(tree, ShouldHaveLength, 2)
+		So(tree[0]["text"], ShouldEqual, "a")
+
+		dir := t.TempDir()
+		one := tWriteFile(dir, "one.tmpl", `{{ . }}`)
+		two := tWriteFile(dir, "two.tmpl", `two`)
+		code, stdout, _ = tRun("", "ast", two, one)
+		So(code, ShouldEqual, 0)
+		var trees map[string][]map[string]any
+		So(json.Unmarshal([]byte(stdout), &trees), ShouldBeNil)
+		So(trees, ShouldHaveLength, 2)
+		So(trees[two][0]["text"], ShouldEqual, "two")
+		So(trees[one][0]["action"], ShouldNotBeNil)
+
+		code, _, stderr := tRun("", "ast", "-json")
+		So(code, ShouldEqual, 2)
+		So(stderr, ShouldContainSubstring, "-json")
+	})
+
+	Convey("strip-comments", t, func() {
+		const input = `{{ _ "a" /* b */ }}{{/* c */}}`
+		const stripped = `{{ _ "a"  }}{{/* c */}}`
+
+		code, stdout, _ := tRun(input, "strip-comments")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, stripped)
+
+		code, stdout, _ = tRun(input, "strip-comments", "-prune")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, stripped)
+
+		// the text scanner does not need a valid template
+		code, stdout, _ = tRun(`{{ if ( /* x */ }}`, "strip-comments")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, `{{ if (  }}`)
+
+		code, stdout, stderr := tRun(`{{ if ( /* x */ }}`, "strip-comments", "-prune")
+		So(code, ShouldEqual, 1)
+		So(stdout, ShouldEqual, "")
+		So(stderr, ShouldStartWith, "tmplstr: <standard input>:1:")
+
+		code, stdout, _ = tRun(input, "strip-comments", "-json")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, `[
+  {
+    "file": "<standard input>",
+    "source": "{{ _ \"a\"  }}{{/* c */}}"
+  }
+]
+`)
+
+		code, _, stderr = tRun(input, "strip-comments", "-w")
+		So(code, ShouldEqual, 1)
+		So(stderr, ShouldContainSubstring, "cannot use -w with standard input")
+
+		dir := t.TempDir()
+		path := tWriteFile(dir, "page.tmpl", input)
+		code, stdout, _ = tRun("", "strip-comments", "-w", path)
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, "")
+		data, err := os.ReadFile(path)
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, stripped)
+		info, err := os.Stat(path)
+		So(err, ShouldBeNil)
+		So(info.Mode().Perm(), ShouldEqual, os.FileMode(0o640))
+
+		// files are not rewritten when standard input is also given
+		other := tWriteFile(dir, "other.tmpl", input)
+		code, _, stderr = tRun(input, "strip-comments", "-w", other, "-")
+		So(code, ShouldEqual, 1)
+		So(stderr, ShouldContainSubstring, "cannot use -w with standard input")
+		data, err = os.ReadFile(other)
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, input)
+	})
+
+	Convey("funcs", t, func() {
+		code, stdout, _ := tRun(tSource, "funcs")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, `<standard input>:2:57: printf (2 args)
+<standard input>:2:74: upper (1 arg)
+<standard input>:2:85: _ (1 arg)
+<standard input>:3:26: __ (3 args)
+`)
+
+		code, stdout, _ = tRun(`{{ len . | print }}`, "funcs", "-json")
+		So(code, ShouldEqual, 0)
+		var calls []map[string]any
+		So(json.Unmarshal([]byte(stdout), &calls), ShouldBeNil)
+		So(calls, ShouldHaveLength, 2)
+		So(calls[0]["name"], ShouldEqual, "len")
+		So(calls[1]["name"], ShouldEqual, "print")
+		So(calls[1]["piped"], ShouldEqual, true)
+
+		_, stdout, _ = tRun(`text`, "funcs", "-json")
+		So(stdout, ShouldEqual, "[]\n")
+	})
+
+	Convey("vars", t, func() {
+		code, stdout, _ := tRun(tSource, "vars")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, `<standard input>:2:23: $x (1 use)
+<standard input>:3:50: $y (0 uses)
+<standard input>:3:50: warning: $y declared and not used (unused-var)
+`)
+
+		code, stdout, _ = tRun(tSource, "vars", "-json")
+		So(code, ShouldEqual, 0)
+		var analysis map[string][]map[string]any
+		So(json.Unmarshal([]byte(stdout), &analysis), ShouldBeNil)
+		So(analysis["decls"], ShouldHaveLength, 2)
+		So(analysis["uses"], ShouldHaveLength, 1)
+		So(analysis["diagnostics"], ShouldHaveLength, 1)
+		So(analysis["diagnostics"][0]["code"], ShouldEqual, "unused-var")
+
+		code, _, stderr := tRun(`{{ end }}`, "vars")
+		So(code, ShouldEqual, 1)
+		So(stderr, ShouldContainSubstring, "unexpected {{end}}")
+	})
+
+	Convey("defines", t, func() {
+		code, stdout, _ := tRun(tSource, "defines")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, `<standard input>:2:1: define "page"
+<standard input>:3:1: block "footer"
+<standard input>:2:97: template "nav"
+`)
+
+		code, stdout, _ = tRun(tSource, "defines", "-json")
+		So(code, ShouldEqual, 0)
+		var graph map[string][]map[string]any
+		So(json.Unmarshal([]byte(stdout), &graph), ShouldBeNil)
+		So(graph["definitions"], ShouldHaveLength, 2)
+		So(graph["references"], ShouldHaveLength, 2)
+
+		code, stdout, _ = tRun(tSource, "defines", "-dot")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldStartWith, "digraph templates {\n")
+		So(stdout, ShouldContainSubstring, `"nav" [shape=box,style=dashed];`)
+
+		code, _, stderr := tRun(`{{ if . }}`, "defines")
+		So(code, ShouldEqual, 1)
+		So(stderr, ShouldContainSubstring, "missing {{end}}")
+	})
+
+	Convey("messages", t, func() {
+		code, stdout, _ := tRun(tSource, "messages")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, `<standard input>:2:87: "Hello"
+<standard input>:3:29: "one" "many"
+`)
+
+		code, stdout, _ = tRun(`{{ T "a" }}{{ _ "b" }}`, "messages", "-funcs", "T")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, "<standard input>:1:6: \"a\"\n")
+
+		code, stdout, _ = tRun(`{{ _ "a" /* note */ }}`, "messages", "-json")
+		So(code, ShouldEqual, 0)
+		var messages []map[string]any
+		So(json.Unmarshal([]byte(stdout), &messages), ShouldBeNil)
+		So(messages, ShouldHaveLength, 1)
+		So(messages[0]["id"], ShouldEqual, "a")
+		So(messages[0]["comment"], ShouldEqual, "note")
+
+		code, stdout, _ = tRun(`{{ _ "a" /* note */ }}`, "messages", "-pot")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldContainSubstring, "#. note\n#: \u2068<standard input>\u2069:1\nmsgid \"a\"\nmsgstr \"\"\n")
+	})
+	Convey("lint", t, func() {
+		const input = "{{ print ((.y)) }}{{ }}x{{ old 1 }}\n"
+		const fixed = "{{ print .y }}x{{ new 1 }}\n"
+
+		code, stdout, stderr := tRun(input, "lint", "-deprecated", "old=new,gone")
+		So(code, ShouldEqual, 1)
+		So(stderr, ShouldEqual, "")
+		So(stdout, ShouldEqual, `<standard input>:1:10: info: redundant parentheses around "(.y)" (redundant-parens)
+<standard input>:1:11: info: redundant parentheses around ".y" (redundant-parens)
+<standard input>:1:19: warning: empty action (empty-action)
+<standard input>:1:28: warning: "old" is deprecated, use "new" instead (deprecated-func)
+`)
+
+		code, stdout, _ = tRun(input, "lint", "-rules", "empty-action")
+		So(code, ShouldEqual, 1)
+		So(stdout, ShouldEqual, "<standard input>:1:19: warning: empty action (empty-action)\n")
+
+		code, stdout, _ = tRun(fixed, "lint")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, "")
+
+		code, _, stderr = tRun(input, "lint", "-rules", "nope")
+		So(code, ShouldEqual, 1)
+		So(stderr, ShouldEqual, "tmplstr: unknown lint rule: \"nope\"\n")
+
+		// the diagnostics are still written when a file fails to lint
+		code, stdout, stderr = tRun(input, "lint", "-json", "-rules", "nope")
+		So(code, ShouldEqual, 1)
+		So(stdout, ShouldEqual, "[]\n")
+		So(stderr, ShouldEqual, "tmplstr: unknown lint rule: \"nope\"\n")
+
+		code, stdout, _ = tRun(`{{ }}`, "lint", "-json")
+		So(code, ShouldEqual, 1)
+		var diagnostics []map[string]any
+		So(json.Unmarshal([]byte(stdout), &diagnostics), ShouldBeNil)
+		So(diagnostics, ShouldHaveLength, 1)
+		So(diagnostics[0]["code"], ShouldEqual, "empty-action")
+		So(diagnostics[0]["fix"], ShouldNotBeNil)
+
+		Convey("fix", func() {
+			code, stdout, stderr = tRun(input, "lint", "-fix", "-deprecated", "old=new")
+			So(code, ShouldEqual, 0)
+			So(stdout, ShouldEqual, fixed)
+			So(stderr, ShouldEqual, "")
+
+			code, stdout, stderr = tRun(input, "lint", "-fix", "-json", "-deprecated", "old")
+			So(code, ShouldEqual, 1)
+			So(stdout, ShouldEqual, "{{ print .y }}x{{ old 1 }}\n")
+			So(stderr, ShouldStartWith, "[")
+			So(stderr, ShouldContainSubstring, `"code": "deprecated-func"`)
+
+			dir := t.TempDir()
+			path := tWriteFile(dir, "page.tmpl", input)
+			code, stdout, _ = tRun("", "lint", "-fix", "-d", "-deprecated", "old=new", path)
+			So(code, ShouldEqual, 0)
+			So(stdout, ShouldEqual, "diff "+path+".orig "+path+"\n--- "+path+".orig\n+++ "+path+"\n@@ -1 +1 @@\n-"+input+"+"+fixed)
+			data, err := os.ReadFile(path)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, input)
+
+			code, stdout, stderr = tRun("", "lint", "-fix", path)
+			So(code, ShouldEqual, 0)
+			So(stdout, ShouldEqual, "")
+			So(stderr, ShouldEqual, "")
+			data, err = os.ReadFile(path)
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, "{{ print .y }}x{{ old 1 }}\n")
+			info, err := os.Stat(path)
+			So(err, ShouldBeNil)
+			So(info.Mode().Perm(), ShouldEqual, os.FileMode(0o640))
+		})
+	})
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command tmplstr inspects text and html template source files.
+//
+// Usage:
+//
+//	tmplstr <command> [flags] [file ...]
+//
+// Without any files, or when a file is "-", the standard input is read. The
+// commands are:
+//
+//	ast             print the JSON abstract syntax tree of each file
+//	strip-comments  remove inline comments from within template actions
+//	funcs           list the function calls made within template actions
+//	vars            list the variable declarations and scope problems
+//	defines         list the define, block and template actions
+//	messages        list the translatable messages
+//	lint            report, and optionally fix, lint problems
+//
+// Every command accepts the -left and -right flags for custom action
+// delimiters and, except for ast which always prints JSON, the -json flag for
+// printing machine readable output. Run "tmplstr <command> -h" for the flags
+// specific to each command.
+package main
+
+import (
+	"os"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
@@ -0,0 +1,193 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/go-corelibs/tmplstr"
+)
+
+const stdinName = "<standard input>"
+
+// command is a single tmplstr subcommand
+type command struct {
+	name  string
+	usage string
+	// raw commands do not need the inputs parsed
+	raw bool
+	// setup registers any command specific flags and returns the function
+	// which runs the command with the parsed inputs
+	setup func(c *context) (fn func(inputs []*input) error)
+}
+
+var commands = []*command{
+	cmdAST,
+	cmdStripComments,
+	cmdFuncs,
+	cmdVars,
+	cmdDefines,
+	cmdMessages,
+	cmdLint,
+}
+
+// context is the state shared by all commands
+type context struct {
+	flags  *flag.FlagSet
+	json   bool
+	parser *tmplstr.Parser
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	// failed is set by commands to exit with a non-zero status without
+	// returning an error
+	failed bool
+}
+
+// input is a single template source file
+type input struct {
+	name   string
+	source string
+	perm   os.FileMode
+	tree   tmplstr.Tree
+}
+
+func usage(w io.Writer) {
+	_, _ = fmt.Fprintf(w, "usage: tmplstr <command> [flags] [file ...]\n\ncommands:\n")
+	for _, cmd := range commands {
+		_, _ = fmt.Fprintf(w, "  %-16s%s\n", cmd.name, cmd.usage)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) (code int) {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+
+	var cmd *command
+	for _, c := range commands {
+		if c.name == args[0] {
+			cmd = c
+			break
+		}
+	}
+	if cmd == nil {
+		if args[0] == "help" || args[0] == "-h" || args[0] == "-help" {
+			usage(stdout)
+			return 0
+		}
+		_, _ = fmt.Fprintf(stderr, "tmplstr: unknown command %q\n", args[0])
+		usage(stderr)
+		return 2
+	}
+
+	c := &context{stdin: stdin, stdout: stdout, stderr: stderr}
+	var left, right string
+	c.flags = flag.NewFlagSet("tmplstr "+cmd.name, flag.ContinueOnError)
+	c.flags.SetOutput(stderr)
+	c.flags.StringVar(&left, "left", tmplstr.DefaultLeftDelim, "left action delimiter")
+	c.flags.StringVar(&right, "right", tmplstr.DefaultRightDelim, "right action delimiter")
+	if cmd != cmdAST {
+		c.flags.BoolVar(&c.json, "json", false, "print JSON output")
+	}
+	fn := cmd.setup(c)
+	c.flags.Usage = func() {
+		_, _ = fmt.Fprintf(stderr, "usage: tmplstr %s [flags] [file ...]\n\n%s\n\nflags:\n", cmd.name, cmd.usage)
+		c.flags.PrintDefaults()
+	}
+	if err := c.flags.Parse(args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	var err error
+	if c.parser, err = tmplstr.NewParser(tmplstr.WithDelims(left, right)); err != nil {
+		_, _ = fmt.Fprintf(stderr, "tmplstr: %v\n", err)
+		return 2
+	}
+
+	inputs, failed := c.read(c.flags.Args(), !cmd.raw)
+	if err = fn(inputs); err != nil {
+		_, _ = fmt.Fprintf(stderr, "tmplstr: %v\n", err)
+		failed = true
+	}
+	if failed || c.failed {
+		return 1
+	}
+	return 0
+}
+
+// read reads and optionally parses all the named files, reporting any errors
+// to stderr and returning only the inputs which were read successfully
+func (c *context) read(names []string, parse bool) (inputs []*input, failed bool) {
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+	for _, name := range names {
+		in := &input{name: name}
+		var data []byte
+		var err error
+		if name == "-" {
+			in.name = stdinName
+			data, err = io.ReadAll(c.stdin)
+		} else {
+			var info os.FileInfo
+			if info, err = os.Stat(name); err == nil {
+				if info.IsDir() {
+					err = fmt.Errorf("%s: is a directory", name)
+				} else {
+					in.perm = info.Mode().Perm()
+					data, err = os.ReadFile(name)
+				}
+			}
+		}
+		if in.source = string(data); err == nil && parse {
+			in.tree, err = c.parser.ParseTemplate(in.name, in.source)
+		}
+		if err != nil {
+			_, _ = fmt.Fprintln(c.stderr, err)
+			failed = true
+			continue
+		}
+		inputs = append(inputs, in)
+	}
+	return
+}
+
+// printJSON writes the indented JSON encoding of the given value to stdout
+func (c *context) printJSON(v any) (err error) {
+	return writeJSON(c.stdout, v)
+}
+
+// writeJSON writes the indented JSON encoding of the given value to w,
+// without escaping the HTML characters common to template source
+func writeJSON(w io.Writer, v any) (err error) {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// printf writes formatted text to stdout
+func (c *context) printf(format string, argv ...any) {
+	_, _ = fmt.Fprintf(c.stdout, format, argv...)
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func tRun(stdin string, args ...string) (code int, stdout, stderr string) {
+	var o, e bytes.Buffer
+	code = run(args, strings.NewReader(stdin), &o, &e)
+	return code, o.String(), e.String()
+}
+
+func tWriteFile(dir, name, content string) (path string) {
+	path = filepath.Join(dir, name)
+	So(os.WriteFile(path, []byte(content), 0o640), ShouldBeNil)
+	return
+}
+
+func TestTmplStr(t *testing.T) {
+	Convey("usage", t, func() {
+		code, stdout, stderr := tRun("")
+		So(code, ShouldEqual, 2)
+		So(stdout, ShouldEqual, "")
+		So(stderr, ShouldStartWith, "usage: tmplstr <command>")
+		So(stderr, ShouldContainSubstring, "strip-comments")
+
+		code, stdout, _ = tRun("", "help")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldStartWith, "usage: tmplstr <command>")
+
+		code, _, stderr = tRun("", "nope")
+		So(code, ShouldEqual, 2)
+		So(stderr, ShouldStartWith, `tmplstr: unknown command "nope"`)
+
+		code, _, stderr = tRun("", "funcs", "-h")
+		So(code, ShouldEqual, 0)
+		So(stderr, ShouldStartWith, "usage: tmplstr funcs [flags] [file ...]")
+		So(stderr, ShouldContainSubstring, "-json")
+
+		code, _, _ = tRun("", "funcs", "-nope")
+		So(code, ShouldEqual, 2)
+
+		code, _, stderr = tRun("", "funcs", "-left", "}}")
+		So(code, ShouldEqual, 2)
+		So(stderr, ShouldContainSubstring, "delimiters are the same")
+	})
+
+	Convey("inputs", t, func() {
+		dir := t.TempDir()
+		one := tWriteFile(dir, "one.tmpl", `{{ print 1 }}`)
+		two := tWriteFile(dir, "two.tmpl", `{{ print 2 }}{{ upper "x" }}`)
+		bad := tWriteFile(dir, "bad.tmpl", `{{ if ( }}`)
+
+		code, stdout, stderr := tRun(`{{ len . }}`, "funcs", "-u")
+		So(code, ShouldEqual, 0)
+		So(stderr, ShouldEqual, "")
+		So(stdout, ShouldEqual, "len\n")
+
+		code, stdout, _ = tRun(`{{ len . }}`, "funcs", "-u", one, "-")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, "print\nlen\n")
+
+		code, stdout, stderr = tRun("", "funcs", "-u", one, bad, two, dir, filepath.Join(dir, "missing.tmpl"))
+		So(code, ShouldEqual, 1)
+		So(stdout, ShouldEqual, "print\nupper\n")
+		So(stderr, ShouldContainSubstring, bad+":1:")
+		So(stderr, ShouldContainSubstring, dir+": is a directory")
+		So(stderr, ShouldContainSubstring, "missing.tmpl")
+
+		code, stdout, _ = tRun(`[[ print ]]{{ len }}`, "funcs", "-u", "-left", "[[", "-right", "]]")
+		So(code, ShouldEqual, 0)
+		So(stdout, ShouldEqual, "print\n")
+	})
+}
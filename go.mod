@@ -6,6 +6,7 @@ require (
 	github.com/alecthomas/participle/v2 v2.1.1
 	github.com/go-corelibs/strings v1.6.0
 	github.com/smartystreets/goconvey v1.8.1
+	golang.org/x/text v0.11.0
 )
 
 require (
@@ -20,5 +21,4 @@ require (
 	github.com/weppos/publicsuffix-go v0.30.1 // indirect
 	golang.org/x/net v0.12.0 // indirect
 	golang.org/x/sys v0.10.0 // indirect
-	golang.org/x/text v0.11.0 // indirect
 )
@@ -0,0 +1,230 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package diff provides unified diff output for the tmplstr commands
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Context is the number of unchanged lines included around each change
+const Context = 3
+
+type opKind byte
+
+const (
+	opEqual  opKind = ' '
+	opDelete opKind = '-'
+	opInsert opKind = '+'
+)
+
+type op struct {
+	kind opKind
+	line string
+	a, b int // line indexes within the old and new texts
+}
+
+// Unified returns the unified diff of the old and new texts, labelled with
+// the given names, or an empty string when the texts are the same
+func Unified(oldName, newName, oldText, newText string) (output string) {
+	if oldText == newText {
+		return ""
+	}
+	ops := compare(splitLines(oldText), splitLines(newText))
+
+	var sb strings.Builder
+	sb.WriteString("--- " + oldName + "\n")
+	sb.WriteString("+++ " + newName + "\n")
+	for start := 0; start < len(ops); {
+		// find the next change
+		for start < len(ops) && ops[start].kind == opEqual {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		// extend the hunk until the gap of equal lines to the next change is
+		// more than 2*Context, as the two hunks would otherwise overlap or
+		// touch
+		end := start + 1
+		for idx := end; idx < len(ops); idx++ {
+			if ops[idx].kind != opEqual {
+				end = idx + 1
+			} else if gap := idx - end + 1; gap > 2*Context {
+				break
+			}
+		}
+		first, last := max(start-Context, 0), min(end+Context, len(ops))
+		writeHunk(&sb, ops[first:last])
+		start = last
+	}
+	return sb.String()
+}
+
+func writeHunk(sb *strings.Builder, ops []op) {
+	var oldStart, oldCount, newStart, newCount int
+	oldStart, newStart = -1, -1
+	for _, o := range ops {
+		if o.kind != opInsert {
+			if oldStart < 0 {
+				oldStart = o.a
+			}
+			oldCount++
+		}
+		if o.kind != opDelete {
+			if newStart < 0 {
+				newStart = o.b
+			}
+			newCount++
+		}
+	}
+	if oldStart < 0 {
+		oldStart = ops[0].a - 1
+	}
+	if newStart < 0 {
+		newStart = ops[0].b - 1
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+	for _, o := range ops {
+		sb.WriteByte(byte(o.kind))
+		sb.WriteString(o.line)
+		if !strings.HasSuffix(o.line, "\n") {
+			sb.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// splitLines returns the lines of the given text, each with its newline
+func splitLines(text string) (lines []string) {
+	lines = strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return
+}
+
+// compare returns the edit script turning a into b, using the linear space
+// variant of Myers' O(ND) algorithm after trimming the common prefix and
+// suffix lines
+func compare(a, b []string) (ops []op) {
+	d := &differ{a: a, b: b}
+	d.compare(0, len(a), 0, len(b))
+	return d.ops
+}
+
+type differ struct {
+	a, b []string
+	ops  []op
+}
+
+// compare appends the edit script turning a[aLo:aHi] into b[bLo:bHi]
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.equal(aLo, bLo, 1)
+		aLo, bLo = aLo+1, bLo+1
+	}
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && d.a[aHi-suffix-1] == d.b[bHi-suffix-1] {
+		suffix++
+	}
+	aEnd, bEnd := aHi-suffix, bHi-suffix
+
+	switch {
+	case aLo == aEnd:
+		for j := bLo; j < bEnd; j++ {
+			d.ops = append(d.ops, op{kind: opInsert, line: d.b[j], a: aLo, b: j})
+		}
+	case bLo == bEnd:
+		for i := aLo; i < aEnd; i++ {
+			d.ops = append(d.ops, op{kind: opDelete, line: d.a[i], a: i, b: bLo})
+		}
+	default:
+		x0, y0, x1, y1 := d.middleSnake(aLo, aEnd, bLo, bEnd)
+		d.compare(aLo, x0, bLo, y0)
+		d.equal(x0, y0, x1-x0)
+		d.compare(x1, aEnd, y1, bEnd)
+	}
+
+	d.equal(aEnd, bEnd, suffix)
+}
+
+// equal appends count equal lines starting at a[i] and b[j]
+func (d *differ) equal(i, j, count int) {
+	for n := 0; n < count; n++ {
+		d.ops = append(d.ops, op{kind: opEqual, line: d.a[i+n], a: i + n, b: j + n})
+	}
+}
+
+// middleSnake returns the start and end of the middle snake of an optimal
+// edit script turning a[aLo:aHi] into b[bLo:bHi], by searching forwards
+// from the start and backwards from the end until the paths overlap. Both
+// ranges must be non-empty
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (x0, y0, x1, y1 int) {
+	n, m := aHi-aLo, bHi-bLo
+	limit := (n + m + 1) / 2
+	delta := n - m
+	odd := delta%2 != 0
+	// forward[off+k] is the furthest x on diagonal k (x-y) from the start and
+	// backward[off+k] is the furthest x on diagonal k from the end
+	off := limit + 1
+	forward, backward := make([]int, 2*off+1), make([]int, 2*off+1)
+
+	for depth := 0; depth <= limit; depth++ {
+		for k := -depth; k <= depth; k += 2 {
+			var x int
+			if k == -depth || (k != depth && forward[off+k-1] < forward[off+k+1]) {
+				x = forward[off+k+1]
+			} else {
+				x = forward[off+k-1] + 1
+			}
+			y := x - k
+			sx, sy := x, y
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x, y = x+1, y+1
+			}
+			forward[off+k] = x
+			if odd && -(depth-1) <= delta-k && delta-k <= depth-1 && x+backward[off+delta-k] >= n {
+				return aLo + sx, bLo + sy, aLo + x, bLo + y
+			}
+		}
+		for k := -depth; k <= depth; k += 2 {
+			var x int
+			if k == -depth || (k != depth && backward[off+k-1] < backward[off+k+1]) {
+				x = backward[off+k+1]
+			} else {
+				x = backward[off+k-1] + 1
+			}
+			y := x - k
+			sx, sy := x, y
+			for x < n && y < m && d.a[aHi-x-1] == d.b[bHi-y-1] {
+				x, y = x+1, y+1
+			}
+			backward[off+k] = x
+			if !odd && -depth <= delta-k && delta-k <= depth && x+forward[off+delta-k] >= n {
+				return aHi - x, bHi - y, aHi - sx, bHi - sy
+			}
+		}
+	}
+	// not reached, the paths always overlap within limit steps
+	return aLo, bLo, aLo, bLo
+}
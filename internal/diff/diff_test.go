@@ -0,0 +1,140 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestUnified(t *testing.T) {
+	Convey("same", t, func() {
+		So(Unified("a", "b", "x\n", "x\n"), ShouldEqual, "")
+	})
+
+	Convey("single hunk", t, func() {
+		So(Unified("a.tmpl", "b.tmpl", "1\n2\n3\n4\n5\n6\n7\n8\n", "1\n2\n3\n4\nfive\n6\n7\n8\n"), ShouldEqual, `--- a.tmpl
++++ b.tmpl
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+`)
+	})
+
+	Convey("multiple hunks", t, func() {
+		old := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n"
+		new := "A\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nL\n"
+		So(Unified("old", "new", old, new), ShouldEqual, `--- old
++++ new
+@@ -1,4 +1,4 @@
+-a
++A
+ b
+ c
+ d
+@@ -9,4 +9,4 @@
+ i
+ j
+ k
+-l
++L
+`)
+	})
+
+	Convey("insertions and missing newlines", t, func() {
+		So(Unified("old", "new", "", "x\n"), ShouldEqual, "--- old\n+++ new\n@@ -0,0 +1 @@\n+x\n")
+		So(Unified("old", "new", "x", "x\n"), ShouldEqual, "--- old\n+++ new\n@@ -1 +1 @@\n-x\n\\ No newline at end of file\n+x\n")
+	})
+
+	Convey("hunk boundaries", t, func() {
+		// six equal lines between changes join the hunks, seven split them
+		So(Unified("old", "new", "x\n1\n2\n3\n4\n5\n6\ny\n", "X\n1\n2\n3\n4\n5\n6\nY\n"), ShouldEqual,
+			"--- old\n+++ new\n@@ -1,8 +1,8 @@\n-x\n+X\n 1\n 2\n 3\n 4\n 5\n 6\n-y\n+Y\n")
+		So(Unified("old", "new", "x\n1\n2\n3\n4\n5\n6\n7\ny\n", "X\n1\n2\n3\n4\n5\n6\n7\nY\n"), ShouldEqual,
+			"--- old\n+++ new\n@@ -1,4 +1,4 @@\n-x\n+X\n 1\n 2\n 3\n@@ -6,4 +6,4 @@\n 5\n 6\n 7\n-y\n+Y\n")
+	})
+
+	Convey("large inputs", t, func() {
+		var old, new strings.Builder
+		for i := 0; i < 50000; i++ {
+			line := strings.Repeat("x", i%7) + "\n"
+			old.WriteString(line)
+			if i != 25000 {
+				new.WriteString(line)
+			}
+		}
+		So(Unified("old", "new", old.String(), new.String()), ShouldContainSubstring, "@@ -24998,7 +24998,6 @@\n")
+	})
+}
+
+func TestCompare(t *testing.T) {
+	Convey("minimal edit scripts", t, func() {
+		random := rand.New(rand.NewSource(1))
+		lines := func() (lines []string) {
+			for n := random.Intn(12); n > 0; n-- {
+				lines = append(lines, string(rune('a'+random.Intn(3))))
+			}
+			return
+		}
+		for idx := 0; idx < 500; idx++ {
+			a, b := lines(), lines()
+			var gotA, gotB []string
+			var edits int
+			for _, o := range compare(a, b) {
+				if o.kind != opInsert {
+					So(o.line, ShouldEqual, a[o.a])
+					gotA = append(gotA, o.line)
+				}
+				if o.kind != opDelete {
+					So(o.line, ShouldEqual, b[o.b])
+					gotB = append(gotB, o.line)
+				}
+				if o.kind != opEqual {
+					edits++
+				}
+			}
+			So(gotA, ShouldEqual, a)
+			So(gotB, ShouldEqual, b)
+			So(edits, ShouldEqual, len(a)+len(b)-2*tLCS(a, b))
+		}
+	})
+}
+
+// tLCS returns the length of the longest common subsequence of a and b
+func tLCS(a, b []string) int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	return lcs[0][0]
+}
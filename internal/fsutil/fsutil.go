@@ -0,0 +1,61 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fsutil provides file system helpers for the tmplstr commands
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// WriteFile writes data to the named file like os.WriteFile, except that
+// the data is first written to a temporary file within the same directory
+// which is then renamed over the named file, so that the named file is
+// never left partially written. Symbolic links are followed so that the
+// link itself is not replaced and the temporary file is given perm before
+// being renamed
+func WriteFile(name string, data []byte, perm os.FileMode) (err error) {
+	if resolved, e := filepath.EvalSymlinks(name); e == nil {
+		name = resolved
+	}
+	dir, base := filepath.Split(name)
+	if dir == "" {
+		dir = "."
+	}
+	var tmp *os.File
+	if tmp, err = os.CreateTemp(dir, "."+base+".*.tmp"); err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	if _, err = tmp.Write(data); err != nil {
+		return
+	}
+	if err = tmp.Chmod(perm); err != nil {
+		return
+	}
+	if err = tmp.Sync(); err != nil {
+		return
+	}
+	if err = tmp.Close(); err != nil {
+		return
+	}
+	err = os.Rename(tmp.Name(), name)
+	return
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWriteFile(t *testing.T) {
+	Convey("replaces the file", t, func() {
+		dir := t.TempDir()
+		name := filepath.Join(dir, "a.tmpl")
+		So(os.WriteFile(name, []byte("old"), 0o600), ShouldBeNil)
+		So(WriteFile(name, []byte("new"), 0o640), ShouldBeNil)
+		data, err := os.ReadFile(name)
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, "new")
+		info, err := os.Stat(name)
+		So(err, ShouldBeNil)
+		So(info.Mode().Perm(), ShouldEqual, os.FileMode(0o640))
+		entries, err := os.ReadDir(dir)
+		So(err, ShouldBeNil)
+		So(entries, ShouldHaveLength, 1)
+	})
+
+	Convey("follows symbolic links", t, func() {
+		dir := t.TempDir()
+		name, link := filepath.Join(dir, "a.tmpl"), filepath.Join(dir, "b.tmpl")
+		So(os.WriteFile(name, []byte("old"), 0o644), ShouldBeNil)
+		So(os.Symlink(name, link), ShouldBeNil)
+		So(WriteFile(link, []byte("new"), 0o644), ShouldBeNil)
+		info, err := os.Lstat(link)
+		So(err, ShouldBeNil)
+		So(info.Mode()&os.ModeSymlink, ShouldNotEqual, 0)
+		data, err := os.ReadFile(name)
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, "new")
+	})
+
+	Convey("missing directory", t, func() {
+		So(WriteFile(filepath.Join(t.TempDir(), "nope", "a.tmpl"), nil, 0o644), ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"fmt"
+
+	"github.com/go-corelibs/tmplstr"
+)
+
+// DefaultMaxPasses is the maximum number of fix passes made when the
+// Fixer.MaxPasses is zero
+const DefaultMaxPasses = 10
+
+// Fixer applies the suggested Fixes of lint Diagnostics to template source
+// text
+type Fixer struct {
+	// Registry is the Registry of Rules to check with, nil for the default
+	// Registry
+	Registry *Registry
+	// Parser is the Parser to parse with, nil for the default action
+	// delimiters
+	Parser *tmplstr.Parser
+	// Rules are the names of the Rules to check with, nil for all of them
+	Rules []string
+	// MaxPasses is the maximum number of fix passes to make, zero for
+	// DefaultMaxPasses
+	MaxPasses int
+}
+
+// FixSource is a convenience wrapper around Fixer.Fix using the default
+// Registry and action delimiters along with the named Rules
+func FixSource(filename, source string, names ...string) (fixed string, remaining Diagnostics, applied int, err error) {
+	return (&Fixer{Rules: names}).Fix(filename, source)
+}
+
+// Fix parses and lints the given source text, applying the suggested Fixes
+// in passes until no more Fixes apply or MaxPasses is reached. Within each
+// pass, Fixes are accepted in Diagnostic order and any Fix overlapping one
+// already accepted is left for the next pass, when the edited source text
+// has been parsed and linted again
+//
+// Fix returns the fixed source text, the Diagnostics remaining after the last
+// pass and the number of Fixes applied. When the source text cannot be
+// parsed, the parse error is returned along with the source text from before
+// the pass which broke it, if any
+func (f *Fixer) Fix(filename, source string) (fixed string, remaining Diagnostics, applied int, err error) {
+	registry, parse, maxPasses := f.Registry, tmplstr.ParseTemplate, f.MaxPasses
+	if registry == nil {
+		registry = gDefaultRegistry
+	}
+	if f.Parser != nil {
+		parse = f.Parser.ParseTemplate
+	}
+	if maxPasses <= 0 {
+		maxPasses = DefaultMaxPasses
+	}
+
+	fixed = source
+	var tree tmplstr.Tree
+	if tree, err = parse(filename, source); err != nil {
+		return
+	}
+	for pass := 1; ; pass++ {
+		if remaining, err = registry.Lint(tree, f.Rules...); err != nil || pass > maxPasses {
+			return
+		}
+
+		var accepted []tmplstr.TextEdit
+		var count int
+		for _, d := range remaining {
+			if d.Fix != nil && len(d.Fix.Edits) > 0 && !overlapping(accepted, d.Fix.Edits) {
+				accepted = append(accepted, d.Fix.Edits...)
+				count += 1
+			}
+		}
+		if count == 0 {
+			return
+		}
+
+		var edited string
+		if edited, err = tmplstr.ApplyEdits(fixed, accepted); err != nil || edited == fixed {
+			return
+		} else if tree, err = parse(filename, edited); err != nil {
+			err = fmt.Errorf("fix pass %d produced an invalid template: %w", pass, err)
+			return
+		}
+		fixed, applied = edited, applied+count
+	}
+}
+
+// overlapping returns true if any of the edits given overlap each other or
+// any of the accepted edits
+func overlapping(accepted, edits []tmplstr.TextEdit) bool {
+	for idx, edit := range edits {
+		for _, other := range accepted {
+			if edit.Overlaps(other) {
+				return true
+			}
+		}
+		for _, other := range edits[idx+1:] {
+			if edit.Overlaps(other) {
+				return true
+			}
+		}
+	}
+	return false
+}
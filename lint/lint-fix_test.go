@@ -0,0 +1,141 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"testing"
+
+	"github.com/alecthomas/participle/v2/lexer"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/go-corelibs/tmplstr"
+)
+
+// tEditRule suggests the same Fix for every Tree checked
+type tEditRule struct {
+	edits []tmplstr.TextEdit
+}
+
+func (tEditRule) Name() string               { return "edit" }
+func (tEditRule) Severity() tmplstr.Severity { return tmplstr.SeverityInfo }
+
+func (r tEditRule) Check(ctx *Context, tree tmplstr.Tree) (diagnostics []Diagnostic) {
+	return []Diagnostic{{
+		Diagnostic: tmplstr.Diagnostic{Message: "edit"},
+		Fix:        &Fix{Message: "edit", Edits: r.edits},
+	}}
+}
+
+func tOffsets(pos, end int, text string) tmplstr.TextEdit {
+	return tmplstr.NewTextEdit(lexer.Position{Offset: pos}, lexer.Position{Offset: end}, text)
+}
+
+func TestFix(t *testing.T) {
+	Convey("passes", t, func() {
+		fixed, remaining, applied, err := FixSource("fix.tmpl", "{{ print ((.y)) }}{{ }}x{{- -}}")
+		So(err, ShouldBeNil)
+		So(fixed, ShouldEqual, "{{ print .y }}x{{- -}}")
+		So(applied, ShouldEqual, 3)
+		So(remaining, ShouldHaveLength, 1)
+		So(remaining[0].String(), ShouldEqual, "fix.tmpl:1:16: warning: empty action (empty-action)")
+
+		fixed, remaining, applied, err = (&Fixer{MaxPasses: 1}).Fix("fix.tmpl", "{{ print ((.y)) }}{{ }}x")
+		So(err, ShouldBeNil)
+		So(fixed, ShouldEqual, "{{ print (.y) }}x")
+		So(applied, ShouldEqual, 2)
+		So(remaining, ShouldHaveLength, 1)
+		So(remaining[0].Code, ShouldEqual, "redundant-parens")
+
+		fixed, remaining, applied, err = FixSource("fix.tmpl", "{{ print ((.y)) }}{{ }}x", "empty-action")
+		So(err, ShouldBeNil)
+		So(fixed, ShouldEqual, "{{ print ((.y)) }}x")
+		So(applied, ShouldEqual, 1)
+		So(remaining, ShouldBeEmpty)
+
+		fixed, remaining, applied, err = FixSource("fix.tmpl", "clean")
+		So(err, ShouldBeNil)
+		So(fixed, ShouldEqual, "clean")
+		So(applied, ShouldEqual, 0)
+		So(remaining, ShouldBeEmpty)
+	})
+
+	Convey("options", t, func() {
+		f := &Fixer{
+			Registry: NewRegistry(&DeprecatedFuncs{Funcs: map[string]string{"old": "new"}}),
+			Parser:   tmplstr.MustNewParser(tmplstr.WithDelims("[[", "]]")),
+		}
+		fixed, remaining, applied, err := f.Fix("fix.tmpl", "[[ old 1 | old ]]{{ old }}")
+		So(err, ShouldBeNil)
+		So(fixed, ShouldEqual, "[[ new 1 | new ]]{{ old }}")
+		So(applied, ShouldEqual, 2)
+		So(remaining, ShouldBeEmpty)
+
+		f.Rules = []string{"nope"}
+		fixed, _, _, err = f.Fix("fix.tmpl", "[[ old ]]")
+		So(err, ShouldNotBeNil)
+		So(fixed, ShouldEqual, "[[ old ]]")
+	})
+
+	Convey("errors", t, func() {
+		fixed, _, applied, err := FixSource("fix.tmpl", "{{ if ( }}")
+		So(err, ShouldNotBeNil)
+		So(fixed, ShouldEqual, "{{ if ( }}")
+		So(applied, ShouldEqual, 0)
+
+		f := &Fixer{Registry: NewRegistry(tEditRule{edits: []tmplstr.TextEdit{tOffsets(2, 2, " (")}})}
+		fixed, remaining, applied, err := f.Fix("fix.tmpl", "{{ . }}")
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldStartWith, "fix pass 1 produced an invalid template: fix.tmpl:1:")
+		So(fixed, ShouldEqual, "{{ . }}")
+		So(applied, ShouldEqual, 0)
+		So(remaining, ShouldHaveLength, 1)
+
+		f = &Fixer{Registry: NewRegistry(tEditRule{edits: []tmplstr.TextEdit{tOffsets(0, 3, ""), tOffsets(2, 4, "")}})}
+		fixed, _, applied, err = f.Fix("fix.tmpl", "{{ . }}")
+		So(err, ShouldBeNil)
+		So(fixed, ShouldEqual, "{{ . }}")
+		So(applied, ShouldEqual, 0)
+
+		f = &Fixer{Registry: NewRegistry(tEditRule{edits: []tmplstr.TextEdit{tOffsets(0, 10, "")}})}
+		fixed, _, _, err = f.Fix("fix.tmpl", "{{ . }}")
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "edit out of range: 0-10 of 7 bytes")
+		So(fixed, ShouldEqual, "{{ . }}")
+	})
+
+	Convey("unstable", t, func() {
+		f := &Fixer{
+			Registry:  NewRegistry(tEditRule{edits: []tmplstr.TextEdit{tOffsets(0, 0, "x")}}),
+			MaxPasses: 3,
+		}
+		fixed, remaining, applied, err := f.Fix("fix.tmpl", "{{ . }}")
+		So(err, ShouldBeNil)
+		So(fixed, ShouldEqual, "xxx{{ . }}")
+		So(applied, ShouldEqual, 3)
+		So(remaining, ShouldHaveLength, 1)
+
+		f.MaxPasses = 0
+		fixed, _, applied, _ = f.Fix("fix.tmpl", "")
+		So(applied, ShouldEqual, DefaultMaxPasses)
+		So(fixed, ShouldEqual, "xxxxxxxxxx")
+
+		// a Fix making no change stops the passes
+		f = &Fixer{Registry: NewRegistry(tEditRule{edits: []tmplstr.TextEdit{tOffsets(0, 2, "{{")}})}
+		fixed, _, applied, err = f.Fix("fix.tmpl", "{{ . }}")
+		So(err, ShouldBeNil)
+		So(fixed, ShouldEqual, "{{ . }}")
+		So(applied, ShouldEqual, 0)
+	})
+}
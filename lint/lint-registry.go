@@ -0,0 +1,136 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/go-corelibs/tmplstr"
+)
+
+var gDefaultRegistry = NewRegistry(BuiltinRules()...)
+
+// Registry is a collection of Rules, indexed by name
+type Registry struct {
+	rules map[string]Rule
+	mu    sync.RWMutex
+}
+
+// NewRegistry constructs a new Registry with the given Rules registered
+func NewRegistry(rules ...Rule) (r *Registry) {
+	r = &Registry{rules: make(map[string]Rule)}
+	r.Register(rules...)
+	return
+}
+
+// Register adds the given Rules to the default Registry, replacing any
+// registered Rules with the same names
+func Register(rules ...Rule) {
+	gDefaultRegistry.Register(rules...)
+}
+
+// Register is the Registry equivalent of the package-level Register function
+func (r *Registry) Register(rules ...Rule) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rule := range rules {
+		r.rules[rule.Name()] = rule
+	}
+}
+
+// Lookup returns the named Rule from the default Registry, nil if not found
+func Lookup(name string) (rule Rule) {
+	return gDefaultRegistry.Lookup(name)
+}
+
+// Lookup is the Registry equivalent of the package-level Lookup function
+func (r *Registry) Lookup(name string) (rule Rule) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.rules[name]
+}
+
+// Rules returns all the Rules within the default Registry, sorted by name
+func Rules() (rules []Rule) {
+	return gDefaultRegistry.Rules()
+}
+
+// Rules is the Registry equivalent of the package-level Rules function
+func (r *Registry) Rules() (rules []Rule) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, rule := range r.rules {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Name() < rules[j].Name()
+	})
+	return
+}
+
+// Lint checks the given Tree with the named Rules of the default Registry,
+// or all of them when no names are given, returning the sorted Diagnostics
+// found. The Code and Severity of each Diagnostic are set to the Name and
+// Severity of the Rule reporting it. An error is returned for any names not
+// registered
+//
+// Diagnostics are suppressed with `tmplstr:ignore` comments listing the rule
+// names to ignore, separated by spaces or commas, or no names to ignore all
+// rules. Anything following a "--" is ignored and can be used to explain the
+// suppression. Comment-only actions suppress Diagnostics within the next
+// action, inline comments suppress Diagnostics within their own action and
+// `tmplstr:ignore-file` comments suppress Diagnostics within the whole Tree:
+//
+//	{{/* tmplstr:ignore redundant-parens -- kept for clarity */}}
+//	{{ print (.Name) }}
+//	{{ print (.Name) /* tmplstr:ignore */ }}
+//	{{/* tmplstr:ignore-file unused-var */}}
+func Lint(tree tmplstr.Tree, names ...string) (diagnostics Diagnostics, err error) {
+	return gDefaultRegistry.Lint(tree, names...)
+}
+
+// Lint is the Registry equivalent of the package-level Lint function
+func (r *Registry) Lint(tree tmplstr.Tree, names ...string) (diagnostics Diagnostics, err error) {
+	var rules []Rule
+	if len(names) == 0 {
+		rules = r.Rules()
+	} else {
+		for _, name := range names {
+			rule := r.Lookup(name)
+			if rule == nil {
+				return nil, fmt.Errorf("unknown lint rule: %q", name)
+			}
+			rules = append(rules, rule)
+		}
+	}
+
+	ctx := NewContext(tree)
+	suppressions := findSuppressions(tree)
+	for _, rule := range rules {
+		for _, d := range rule.Check(ctx, tree) {
+			d.Code, d.Severity = rule.Name(), rule.Severity()
+			if d.Pos.Filename == "" {
+				d.Pos.Filename = ctx.Filename
+			}
+			if !suppressions.suppressed(d) {
+				diagnostics = append(diagnostics, d)
+			}
+		}
+	}
+	diagnostics.Sort()
+	return
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/go-corelibs/tmplstr"
+)
+
+// tTodoRule reports TODO comments
+type tTodoRule struct{}
+
+func (tTodoRule) Name() string               { return "todo" }
+func (tTodoRule) Severity() tmplstr.Severity { return tmplstr.SeverityError }
+
+func (tTodoRule) Check(ctx *Context, tree tmplstr.Tree) (diagnostics []Diagnostic) {
+	for _, binding := range tmplstr.BindComments(tree) {
+		if strings.HasPrefix(binding.Text, "TODO") {
+			pos := binding.Comment.Pos
+			pos.Filename = ""
+			diagnostics = append(diagnostics, Diagnostic{
+				Diagnostic: tmplstr.Diagnostic{Pos: pos, Code: "ignored", Severity: tmplstr.SeverityInfo, Message: binding.Text},
+			})
+		}
+	}
+	return
+}
+
+func TestRegistry(t *testing.T) {
+	Convey("default", t, func() {
+		var names []string
+		for _, rule := range Rules() {
+			names = append(names, rule.Name())
+		}
+		So(names, ShouldEqual, []string{"deprecated-func", "empty-action", "mixed-trim", "redundant-parens", "unused-var"})
+		So(Lookup("empty-action"), ShouldHaveSameTypeAs, EmptyAction{})
+		So(Lookup("nope"), ShouldBeNil)
+	})
+
+	Convey("custom", t, func() {
+		r := NewRegistry()
+		So(r.Rules(), ShouldBeEmpty)
+		So(tLint(r, `{{ }}`), ShouldBeEmpty)
+
+		r.Register(tTodoRule{}, EmptyAction{})
+		So(tLint(r, `{{/* TODO: fix */}}{{ }}`), ShouldEqual, []string{
+			"lint.tmpl:1:3: error: TODO: fix (todo)",
+			"lint.tmpl:1:20: warning: empty action (empty-action)",
+		})
+		So(tLint(r, `{{/* TODO: fix */}}{{ }}`, "empty-action"), ShouldEqual, []string{
+			"lint.tmpl:1:20: warning: empty action (empty-action)",
+		})
+
+		_, err := r.Lint(tParse(`{{ }}`), "todo", "nope")
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, `unknown lint rule: "nope"`)
+	})
+
+	Convey("replacing", t, func() {
+		r := NewRegistry(BuiltinRules()...)
+		So(tLint(r, `{{ old . }}`), ShouldBeEmpty)
+		r.Register(&DeprecatedFuncs{Funcs: map[string]string{"old": "new"}})
+		So(r.Rules(), ShouldHaveLength, 5)
+		So(tLint(r, `{{ old . }}`), ShouldEqual, []string{
+			`lint.tmpl:1:4: warning: "old" is deprecated, use "new" instead (deprecated-func)`,
+		})
+	})
+}
@@ -0,0 +1,291 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-corelibs/tmplstr"
+)
+
+var rxFuncName = regexp.MustCompile(`^[\p{L}_][\p{L}\p{N}_]*$`)
+
+// BuiltinRules returns new instances of all the builtin Rules:
+//
+//	empty-action      actions without any pipeline, like `{{ }}`
+//	redundant-parens  parentheses around a single operand or a whole command
+//	unused-var        variables declared and never used
+//	deprecated-func   calls to deprecated functions, see DeprecatedFuncs
+//	mixed-trim        block actions not trimmed like their opening action
+func BuiltinRules() (rules []Rule) {
+	return []Rule{
+		EmptyAction{},
+		RedundantParens{},
+		UnusedVar{},
+		&DeprecatedFuncs{},
+		MixedTrim{},
+	}
+}
+
+// EmptyAction reports actions containing nothing but whitespace, like `{{ }}`
+// and `{{- -}}`. The Fix suggested removes the action, unless it has trim
+// markers which change the output
+type EmptyAction struct{}
+
+func (EmptyAction) Name() string               { return "empty-action" }
+func (EmptyAction) Severity() tmplstr.Severity { return tmplstr.SeverityWarning }
+
+func (EmptyAction) Check(ctx *Context, tree tmplstr.Tree) (diagnostics []Diagnostic) {
+	for _, branch := range tree {
+		if branch.Action == nil || !isEmptyAction(branch.Action) {
+			continue
+		}
+		d := Diagnostic{Diagnostic: tmplstr.Diagnostic{Pos: branch.Action.Pos, Message: "empty action"}}
+		if trimStyle(branch.Action) == "" {
+			d.Fix = &Fix{
+				Message: "remove the empty action",
+				Edits:   []tmplstr.TextEdit{tmplstr.NewTextEdit(branch.Action.Pos, branch.Action.EndPos, "")},
+			}
+		}
+		diagnostics = append(diagnostics, d)
+	}
+	return
+}
+
+func isEmptyAction(action *tmplstr.Action) bool {
+	for _, pipeline := range action.Pipelines {
+		if pipeline.Pipe != nil {
+			return false
+		}
+		for _, v := range pipeline.Root {
+			if v.Space == nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// RedundantParens reports parentheses which have no effect: those around a
+// single operand, like `(.Name)` in `{{ print (.Name) }}`, and those around
+// the whole first command of an action, like `{{ if (eq .A .B) }}`. The Fix
+// suggested removes the parentheses
+type RedundantParens struct{}
+
+func (RedundantParens) Name() string               { return "redundant-parens" }
+func (RedundantParens) Severity() tmplstr.Severity { return tmplstr.SeverityInfo }
+
+func (RedundantParens) Check(ctx *Context, tree tmplstr.Tree) (diagnostics []Diagnostic) {
+	var found []*tmplstr.Variable
+	seen := make(map[*tmplstr.Variable]bool)
+	add := func(v *tmplstr.Variable) {
+		if !seen[v] {
+			seen[v] = true
+			found = append(found, v)
+		}
+	}
+
+	for _, branch := range tree {
+		if branch.Action == nil {
+			continue
+		}
+		// groupings called with arguments, like `(.Func) 1`, are not the
+		// same as calling the method directly, like `.Func 1`
+		heads := make(map[*tmplstr.Variable]bool)
+		markHeads := func(s *tmplstr.Statement) {
+			for _, command := range s.Commands {
+				if len(command) > 1 {
+					heads[command[0]] = true
+				}
+			}
+		}
+
+		s := branch.Action.Statement()
+		markHeads(s)
+		if len(s.Commands) > 0 && len(s.Commands[0]) == 1 {
+			if v := s.Commands[0][0]; isPlainGrouping(v) {
+				add(v)
+			}
+		}
+		var groupings []*tmplstr.Variable
+		branch.Action.WalkVariables(func(variables *tmplstr.Variables) (stop bool) {
+			for _, v := range *variables {
+				if v.Grouping != nil && v.Grouping.Group != nil {
+					markHeads(v.Grouping.Statement())
+					groupings = append(groupings, v)
+				}
+			}
+			return
+		})
+		for _, v := range groupings {
+			if isPlainGrouping(v) && !heads[v] {
+				if inner := v.Grouping.Statement(); len(inner.Commands) == 1 && len(inner.Commands[0]) == 1 && isOperand(inner.Commands[0][0]) {
+					add(v)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].Pos.Offset < found[j].Pos.Offset
+	})
+	for _, v := range found {
+		inner := strings.TrimSpace(v.Grouping.Group.Render())
+		diagnostics = append(diagnostics, Diagnostic{
+			Diagnostic: tmplstr.Diagnostic{Pos: v.Pos, Message: fmt.Sprintf("redundant parentheses around %q", inner)},
+			Fix: &Fix{
+				Message: "remove the parentheses",
+				Edits:   []tmplstr.TextEdit{tmplstr.NewTextEdit(v.Pos, v.EndPos, inner)},
+			},
+		})
+	}
+	return
+}
+
+// isPlainGrouping returns true if the given Variable is a Grouping without a
+// Field chain or any variable declarations
+func isPlainGrouping(v *tmplstr.Variable) bool {
+	return v.Grouping != nil && v.Grouping.Field == nil && v.Grouping.Group != nil && v.Grouping.Statement().Declare == nil
+}
+
+// isOperand returns true if the given Variable is a value which is not a
+// function call
+func isOperand(v *tmplstr.Variable) bool {
+	return v.Bool != nil || v.Nil != nil || v.Keyword != nil || v.Dot != nil ||
+		v.Literal != nil || v.String != nil || v.Rune != nil || v.Number != nil ||
+		v.Float != nil || v.Int != nil || v.Grouping != nil
+}
+
+// UnusedVar reports variables which are declared and never used
+type UnusedVar struct{}
+
+func (UnusedVar) Name() string               { return "unused-var" }
+func (UnusedVar) Severity() tmplstr.Severity { return tmplstr.SeverityWarning }
+
+func (UnusedVar) Check(ctx *Context, tree tmplstr.Tree) (diagnostics []Diagnostic) {
+	analysis, err := ctx.Scopes()
+	if err != nil {
+		return
+	}
+	for _, decl := range analysis.Decls {
+		if !decl.Used() {
+			diagnostics = append(diagnostics, Diagnostic{
+				Diagnostic: tmplstr.Diagnostic{Pos: decl.Pos, Message: decl.Name + " declared and not used"},
+			})
+		}
+	}
+	return
+}
+
+// DeprecatedFuncs reports calls to the deprecated functions listed in Funcs.
+// When the replacement given is a function name, the Fix suggested calls the
+// replacement instead
+//
+// The builtin DeprecatedFuncs has no Funcs listed, register a configured
+// instance to replace it:
+//
+//	lint.Register(&lint.DeprecatedFuncs{Funcs: map[string]string{
+//		"oldName": "newName",
+//	}})
+type DeprecatedFuncs struct {
+	// Funcs maps deprecated function names to their replacements, which may
+	// be empty when there is no replacement
+	Funcs map[string]string
+}
+
+func (*DeprecatedFuncs) Name() string               { return "deprecated-func" }
+func (*DeprecatedFuncs) Severity() tmplstr.Severity { return tmplstr.SeverityWarning }
+
+func (r *DeprecatedFuncs) Check(ctx *Context, tree tmplstr.Tree) (diagnostics []Diagnostic) {
+	if len(r.Funcs) == 0 {
+		return
+	}
+	for _, call := range tmplstr.FuncCalls(tree) {
+		replacement, deprecated := r.Funcs[call.Name]
+		if !deprecated {
+			continue
+		}
+		d := Diagnostic{Diagnostic: tmplstr.Diagnostic{Pos: call.Pos, Message: fmt.Sprintf("%q is deprecated", call.Name)}}
+		if replacement != "" {
+			d.Message += fmt.Sprintf(", use %q instead", replacement)
+			if rxFuncName.MatchString(replacement) {
+				d.Fix = &Fix{
+					Message: "call " + replacement + " instead",
+					Edits:   []tmplstr.TextEdit{tmplstr.NewTextEdit(call.Ident.Pos, call.Ident.EndPos, replacement)},
+				}
+			}
+		}
+		diagnostics = append(diagnostics, d)
+	}
+	return
+}
+
+// MixedTrim reports {{else}} and {{end}} actions which do not use the same
+// trim markers as the opening action of their block, like `{{- if .A -}}`
+// ending with `{{ end }}`
+type MixedTrim struct{}
+
+func (MixedTrim) Name() string               { return "mixed-trim" }
+func (MixedTrim) Severity() tmplstr.Severity { return tmplstr.SeverityInfo }
+
+func (MixedTrim) Check(ctx *Context, tree tmplstr.Tree) (diagnostics []Diagnostic) {
+	blocks, err := tree.Structure()
+	if err != nil {
+		return
+	}
+	var walk func(blocks tmplstr.BlockTree)
+	walk = func(blocks tmplstr.BlockTree) {
+		for _, node := range blocks {
+			if node.Block == nil {
+				continue
+			}
+			block := node.Block
+			style := trimStyle(block.Open.Action)
+			check := func(branch *tmplstr.Branch, keyword string) {
+				if branch != nil && branch.Action != nil && trimStyle(branch.Action) != style {
+					diagnostics = append(diagnostics, Diagnostic{
+						Diagnostic: tmplstr.Diagnostic{
+							Pos:     branch.Action.Pos,
+							Message: fmt.Sprintf("{{%s}} trim markers differ from the opening {{%s}}", keyword, block.Keyword),
+						},
+					})
+				}
+			}
+			walk(block.Body)
+			for _, clause := range block.Else {
+				check(clause.Open, clause.Keyword)
+				walk(clause.Body)
+			}
+			check(block.End, "end")
+		}
+	}
+	walk(blocks)
+	return
+}
+
+// trimStyle returns a description of the trim markers used by the given
+// Action
+func trimStyle(action *tmplstr.Action) (style string) {
+	if strings.HasSuffix(*action.Open, "-") {
+		style += "left"
+	}
+	if strings.HasPrefix(*action.Close, "-") {
+		style += "right"
+	}
+	return
+}
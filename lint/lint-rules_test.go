@@ -0,0 +1,110 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRules(t *testing.T) {
+	Convey("empty-action", t, func() {
+		diagnostics, err := Lint(tParse("a{{ }}b{{- \n -}}c{{ /* note */ }}"), "empty-action")
+		So(err, ShouldBeNil)
+		So(diagnostics, ShouldHaveLength, 2)
+		So(diagnostics[0].String(), ShouldEqual, "lint.tmpl:1:2: warning: empty action (empty-action)")
+		So(diagnostics[0].Fix, ShouldNotBeNil)
+		So(diagnostics[0].Fix.Edits, ShouldHaveLength, 1)
+		So(diagnostics[0].Fix.Edits[0].Pos.Offset, ShouldEqual, 1)
+		So(diagnostics[0].Fix.Edits[0].End.Offset, ShouldEqual, 6)
+		So(diagnostics[0].Fix.Edits[0].Text, ShouldEqual, "")
+		// trim markers change the output, no fix
+		So(diagnostics[1].Pos.Offset, ShouldEqual, 7)
+		So(diagnostics[1].Fix, ShouldBeNil)
+	})
+
+	Convey("redundant-parens", t, func() {
+		So(tLint(nil, `{{ print (.x) ("a") (1) ($) (.) ($v.x) }}`, "redundant-parens"), ShouldHaveLength, 6)
+		So(tLint(nil, `{{ if (eq .a 1) }}{{ else if (.b) }}{{ end }}`, "redundant-parens"), ShouldEqual, []string{
+			`lint.tmpl:1:7: info: redundant parentheses around "eq .a 1" (redundant-parens)`,
+			`lint.tmpl:1:30: info: redundant parentheses around ".b" (redundant-parens)`,
+		})
+		So(tLint(nil, `{{ $x := (len .) }}{{ template "x" (print .) }}{{ (print 1) | upper }}`, "redundant-parens"), ShouldEqual, []string{
+			`lint.tmpl:1:10: info: redundant parentheses around "len ." (redundant-parens)`,
+			`lint.tmpl:1:36: info: redundant parentheses around "print ." (redundant-parens)`,
+			`lint.tmpl:1:51: info: redundant parentheses around "print 1" (redundant-parens)`,
+		})
+		// nested parentheses are each redundant
+		So(tLint(nil, `{{ print ((.y)) }}`, "redundant-parens"), ShouldEqual, []string{
+			`lint.tmpl:1:10: info: redundant parentheses around "(.y)" (redundant-parens)`,
+			`lint.tmpl:1:11: info: redundant parentheses around ".y" (redundant-parens)`,
+		})
+		So(tLint(nil, `{{ print (now) (len .) (index .x 0).Name ($x := 1) }}{{ (.f) 1 }}{{ print ((.f) 1) }}`, "redundant-parens"), ShouldBeEmpty)
+
+		diagnostics, err := Lint(tParse(`{{ print ( .x /* c */ ) }}`), "redundant-parens")
+		So(err, ShouldBeNil)
+		So(diagnostics, ShouldHaveLength, 1)
+		So(diagnostics[0].Fix.Message, ShouldEqual, "remove the parentheses")
+		So(diagnostics[0].Fix.Edits[0].Pos.Offset, ShouldEqual, 9)
+		So(diagnostics[0].Fix.Edits[0].End.Offset, ShouldEqual, 23)
+		So(diagnostics[0].Fix.Edits[0].Text, ShouldEqual, ".x /* c */")
+	})
+
+	Convey("unused-var", t, func() {
+		So(tLint(nil, `{{ $x := 1 }}{{ $y := 2 }}{{ $y }}{{ range $i, $e := . }}{{ $e }}{{ end }}`, "unused-var"), ShouldEqual, []string{
+			"lint.tmpl:1:4: warning: $x declared and not used (unused-var)",
+			"lint.tmpl:1:44: warning: $i declared and not used (unused-var)",
+		})
+		So(tLint(nil, `{{ $x := 1 }}{{ end }}`, "unused-var"), ShouldBeEmpty)
+	})
+
+	Convey("deprecated-func", t, func() {
+		r := NewRegistry(&DeprecatedFuncs{Funcs: map[string]string{
+			"old":   "new",
+			"gone":  "",
+			"other": "the markdown pipeline",
+		}})
+		So(tLint(r, `{{ old 1 | gone }}{{ other . }}{{ new 1 }}`), ShouldEqual, []string{
+			`lint.tmpl:1:4: warning: "old" is deprecated, use "new" instead (deprecated-func)`,
+			`lint.tmpl:1:12: warning: "gone" is deprecated (deprecated-func)`,
+			`lint.tmpl:1:22: warning: "other" is deprecated, use "the markdown pipeline" instead (deprecated-func)`,
+		})
+
+		diagnostics, err := r.Lint(tParse(`{{ old 1 | gone }}{{ other . }}`))
+		So(err, ShouldBeNil)
+		So(diagnostics, ShouldHaveLength, 3)
+		So(diagnostics[0].Fix, ShouldNotBeNil)
+		So(diagnostics[0].Fix.Message, ShouldEqual, "call new instead")
+		So(diagnostics[0].Fix.Edits[0].Pos.Offset, ShouldEqual, 3)
+		So(diagnostics[0].Fix.Edits[0].End.Offset, ShouldEqual, 6)
+		So(diagnostics[0].Fix.Edits[0].Text, ShouldEqual, "new")
+		So(diagnostics[1].Fix, ShouldBeNil)
+		So(diagnostics[2].Fix, ShouldBeNil)
+	})
+
+	Convey("mixed-trim", t, func() {
+		So(tLint(nil, `{{- if .x -}}a{{- else -}}b{{- end -}}{{ range . }}{{ end }}`, "mixed-trim"), ShouldBeEmpty)
+		So(tLint(nil, `{{- if .x -}}a{{ else if .y }}b{{- else -}}{{- end }}`, "mixed-trim"), ShouldEqual, []string{
+			"lint.tmpl:1:15: info: {{else if}} trim markers differ from the opening {{if}} (mixed-trim)",
+			"lint.tmpl:1:44: info: {{end}} trim markers differ from the opening {{if}} (mixed-trim)",
+		})
+		So(tLint(nil, `{{ define "x" }}{{ with . -}}{{ end }}{{- end }}`, "mixed-trim"), ShouldEqual, []string{
+			"lint.tmpl:1:30: info: {{end}} trim markers differ from the opening {{with}} (mixed-trim)",
+			"lint.tmpl:1:39: info: {{end}} trim markers differ from the opening {{define}} (mixed-trim)",
+		})
+		So(tLint(nil, `{{- if .x }}`, "mixed-trim"), ShouldBeEmpty)
+	})
+}
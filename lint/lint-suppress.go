@@ -0,0 +1,82 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"strings"
+
+	"github.com/go-corelibs/tmplstr"
+)
+
+const (
+	// IgnoreDirective is the comment prefix suppressing Diagnostics within a
+	// single action
+	IgnoreDirective = "tmplstr:ignore"
+	// IgnoreFileDirective is the comment prefix suppressing Diagnostics
+	// within the whole Tree
+	IgnoreFileDirective = "tmplstr:ignore-file"
+)
+
+// suppression is a single parsed `tmplstr:ignore` comment
+type suppression struct {
+	rules      []string // empty for all rules
+	start, end int      // offset range, end is -1 for the whole file
+}
+
+type suppressions []suppression
+
+// findSuppressions returns all the suppressions within the given Tree
+func findSuppressions(tree tmplstr.Tree) (found suppressions) {
+	for _, binding := range tmplstr.BindComments(tree) {
+		var s suppression
+		var rest string
+		var ok bool
+		if rest, ok = strings.CutPrefix(binding.Text, IgnoreFileDirective); ok {
+			s.end = -1
+		} else if rest, ok = strings.CutPrefix(binding.Text, IgnoreDirective); ok && binding.Action != nil {
+			s.start, s.end = binding.Action.Pos.Offset, binding.Action.EndPos.Offset
+		} else {
+			continue
+		}
+		if rest != "" && rest[0] != ' ' && rest[0] != '\t' && rest[0] != '\n' && rest[0] != ',' {
+			// not the directive, such as "tmplstr:ignored"
+			continue
+		}
+		rest, _, _ = strings.Cut(rest, "--")
+		s.rules = strings.FieldsFunc(rest, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+		})
+		found = append(found, s)
+	}
+	return
+}
+
+// suppressed returns true if the given Diagnostic is suppressed
+func (ss suppressions) suppressed(d Diagnostic) bool {
+	for _, s := range ss {
+		if s.end >= 0 && (d.Pos.Offset < s.start || d.Pos.Offset >= s.end) {
+			continue
+		}
+		if len(s.rules) == 0 {
+			return true
+		}
+		for _, rule := range s.rules {
+			if rule == d.Code {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSuppress(t *testing.T) {
+	Convey("next action", t, func() {
+		So(tLint(nil, "{{/* tmplstr:ignore */}}\n{{ }}{{ }}"), ShouldEqual, []string{
+			"lint.tmpl:2:6: warning: empty action (empty-action)",
+		})
+		So(tLint(nil, "{{/* tmplstr:ignore unused-var */}}{{ }}"), ShouldEqual, []string{
+			"lint.tmpl:1:36: warning: empty action (empty-action)",
+		})
+		So(tLint(nil, "{{/* tmplstr:ignore unused-var, empty-action -- on purpose */}}{{ }}"), ShouldBeEmpty)
+		So(tLint(nil, "{{/* tmplstr:ignore -- all rules */}}{{ }}"), ShouldBeEmpty)
+		So(tLint(nil, "{{/* tmplstr:ignore */}}{{/* note */}}{{ }}"), ShouldBeEmpty)
+		So(tLint(nil, "{{ }}{{/* tmplstr:ignore */}}"), ShouldEqual, []string{
+			"lint.tmpl:1:1: warning: empty action (empty-action)",
+		})
+	})
+
+	Convey("inline", t, func() {
+		So(tLint(nil, `{{ print (.x) /* tmplstr:ignore redundant-parens */ }}{{ print (.y) }}`), ShouldEqual, []string{
+			`lint.tmpl:1:64: info: redundant parentheses around ".y" (redundant-parens)`,
+		})
+	})
+
+	Convey("file", t, func() {
+		So(tLint(nil, "{{ $x := 1 }}{{ (.y) }}\n{{/* tmplstr:ignore-file unused-var */}}"), ShouldEqual, []string{
+			`lint.tmpl:1:17: info: redundant parentheses around ".y" (redundant-parens)`,
+		})
+		So(tLint(nil, "{{/* tmplstr:ignore-file */}}{{ $x := 1 }}{{ (.y) }}"), ShouldBeEmpty)
+	})
+
+	Convey("not directives", t, func() {
+		So(tLint(nil, "{{/* tmplstr:ignored */}}{{ }}"), ShouldHaveLength, 1)
+		So(tLint(nil, "{{/* see tmplstr:ignore */}}{{ }}"), ShouldHaveLength, 1)
+	})
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lint provides a template linter framework built on tmplstr.Tree
+//
+// A Rule checks a single Tree and returns the problems found as Diagnostics,
+// optionally with a suggested Fix. Rules are collected in a Registry and the
+// package-level functions use a default Registry with all the builtin rules
+// registered, see BuiltinRules
+//
+// Diagnostics can be suppressed with `tmplstr:ignore` comments, see Lint for
+// the details
+package lint
+
+import (
+	"sort"
+
+	"github.com/go-corelibs/tmplstr"
+)
+
+// Rule is a single lint check
+type Rule interface {
+	// Name returns the unique name of this Rule, used as the Code of the
+	// Diagnostics reported and within `tmplstr:ignore` comments
+	Name() string
+	// Severity returns the Severity of the Diagnostics reported
+	Severity() tmplstr.Severity
+	// Check returns the problems found within the given Tree. The Code and
+	// Severity of the Diagnostics returned are set by the Registry
+	Check(ctx *Context, tree tmplstr.Tree) []Diagnostic
+}
+
+// Diagnostic is a tmplstr.Diagnostic reported by a Rule, with an optional
+// suggested Fix
+type Diagnostic struct {
+	tmplstr.Diagnostic
+	// Fix is the suggested change resolving the problem, nil if there is none
+	Fix *Fix `json:"fix,omitempty"`
+}
+
+// Fix is a suggested change to the template source text
+type Fix struct {
+	// Message describes the change
+	Message string `json:"message"`
+	// Edits are the non-overlapping source text changes to make, see
+	// tmplstr.ApplyEdits
+	Edits []tmplstr.TextEdit `json:"edits"`
+}
+
+// Diagnostics is a list of Diagnostic instances
+type Diagnostics []Diagnostic
+
+// Diagnostics returns the tmplstr.Diagnostic of each of these Diagnostics,
+// without their Fixes
+func (ds Diagnostics) Diagnostics() (diagnostics tmplstr.Diagnostics) {
+	diagnostics = make(tmplstr.Diagnostics, len(ds))
+	for idx, d := range ds {
+		diagnostics[idx] = d.Diagnostic
+	}
+	return
+}
+
+// Sort orders these Diagnostics by filename, offset and code, see
+// tmplstr.Diagnostic.Less
+func (ds Diagnostics) Sort() {
+	sort.SliceStable(ds, func(i, j int) bool {
+		return ds[i].Less(ds[j].Diagnostic)
+	})
+}
+
+// HasErrors returns true if any of these Diagnostics are SeverityError
+func (ds Diagnostics) HasErrors() bool {
+	return ds.Diagnostics().HasErrors()
+}
+
+// Context is the per-Tree state shared by all Rules during a Lint call
+type Context struct {
+	// Filename is the name of the template being checked
+	Filename string
+	// Source is the template source text
+	Source string
+
+	tree     tmplstr.Tree
+	scopes   *tmplstr.ScopeAnalysis
+	scopeErr error
+	scoped   bool
+}
+
+// NewContext constructs a new Context for the given Tree
+func NewContext(tree tmplstr.Tree) (ctx *Context) {
+	ctx = &Context{Source: tree.Render(), tree: tree}
+	if len(tree) > 0 {
+		ctx.Filename = tree[0].Pos.Filename
+	}
+	return
+}
+
+// Scopes returns the tmplstr.AnalyzeScopes results for the Tree, computed
+// once and shared by all Rules
+func (ctx *Context) Scopes() (analysis *tmplstr.ScopeAnalysis, err error) {
+	if !ctx.scoped {
+		ctx.scoped = true
+		ctx.scopes, ctx.scopeErr = tmplstr.AnalyzeScopes(ctx.tree)
+	}
+	return ctx.scopes, ctx.scopeErr
+}
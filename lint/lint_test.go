@@ -0,0 +1,95 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/alecthomas/participle/v2/lexer"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/go-corelibs/tmplstr"
+)
+
+func tParse(input string) (tree tmplstr.Tree) {
+	tree, err := tmplstr.ParseTemplate("lint.tmpl", input)
+	So(err, ShouldBeNil)
+	return
+}
+
+// tLint returns the String form of the Diagnostics found by the given
+// Registry, or the default Registry when nil
+func tLint(r *Registry, input string, names ...string) (found []string) {
+	if r == nil {
+		r = gDefaultRegistry
+	}
+	diagnostics, err := r.Lint(tParse(input), names...)
+	So(err, ShouldBeNil)
+	for _, d := range diagnostics {
+		found = append(found, d.String())
+	}
+	return
+}
+
+func TestLint(t *testing.T) {
+	Convey("Diagnostics", t, func() {
+		ds := Diagnostics{
+			{Diagnostic: tmplstr.Diagnostic{Pos: lexer.Position{Filename: "b", Offset: 1}, Code: "a", Severity: tmplstr.SeverityInfo}},
+			{Diagnostic: tmplstr.Diagnostic{Pos: lexer.Position{Filename: "a", Offset: 5}, Code: "a", Severity: tmplstr.SeverityWarning}},
+			{Diagnostic: tmplstr.Diagnostic{Pos: lexer.Position{Filename: "a", Offset: 1}, Code: "b", Severity: tmplstr.SeverityInfo}},
+			{Diagnostic: tmplstr.Diagnostic{Pos: lexer.Position{Filename: "a", Offset: 1}, Code: "a", Severity: tmplstr.SeverityInfo}},
+		}
+		ds.Sort()
+		So(ds[0].Pos.Filename+ds[0].Code, ShouldEqual, "aa")
+		So(ds[1].Pos.Filename+ds[1].Code, ShouldEqual, "ab")
+		So(ds[2].Pos.Offset, ShouldEqual, 5)
+		So(ds[3].Pos.Filename, ShouldEqual, "b")
+		So(ds.HasErrors(), ShouldBeFalse)
+		ds[2].Severity = tmplstr.SeverityError
+		So(ds.HasErrors(), ShouldBeTrue)
+		plain := ds.Diagnostics()
+		So(plain, ShouldHaveLength, 4)
+		So(plain[2], ShouldResemble, ds[2].Diagnostic)
+	})
+
+	Convey("JSON", t, func() {
+		diagnostics, err := Lint(tParse(`{{ print (.x) }}`))
+		So(err, ShouldBeNil)
+		So(diagnostics, ShouldHaveLength, 1)
+		data, err := json.Marshal(diagnostics[0])
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, `{"pos":{"Filename":"lint.tmpl","Offset":9,"Line":1,"Column":10},"severity":"info","code":"redundant-parens","message":"redundant parentheses around \".x\"","fix":{"message":"remove the parentheses","edits":[{"pos":{"Filename":"lint.tmpl","Offset":9,"Line":1,"Column":10},"end":{"Filename":"lint.tmpl","Offset":13,"Line":1,"Column":14},"text":".x"}]}}`)
+	})
+
+	Convey("Context", t, func() {
+		ctx := NewContext(tParse(`a{{ $x := 1 }}`))
+		So(ctx.Filename, ShouldEqual, "lint.tmpl")
+		So(ctx.Source, ShouldEqual, `a{{ $x := 1 }}`)
+		analysis, err := ctx.Scopes()
+		So(err, ShouldBeNil)
+		So(analysis.Decls, ShouldHaveLength, 1)
+		again, _ := ctx.Scopes()
+		So(again, ShouldEqual, analysis)
+
+		ctx = NewContext(tParse(`{{ end }}`))
+		_, err = ctx.Scopes()
+		So(err, ShouldNotBeNil)
+
+		ctx = NewContext(nil)
+		So(ctx.Filename, ShouldEqual, "")
+		So(ctx.Source, ShouldEqual, "")
+	})
+}
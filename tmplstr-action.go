@@ -14,6 +14,12 @@
 
 package tmplstr
 
+import (
+	"strings"
+
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
 // Action represents a single text or html template action
 //
 // See: https://pkg.go.dev/text/template#hdr-Actions
@@ -21,6 +27,9 @@ type Action struct {
 	Open      *string   `parser:"( @StatementOpen "   json:"open,omitempty"`
 	Pipelines Pipelines `parser:"  @@"                json:"pipelines,omitempty"`
 	Close     *string   `parser:"  @StatementClose )" json:"close,omitempty"`
+
+	Pos    lexer.Position `parser:"" json:"-"`
+	EndPos lexer.Position `parser:"" json:"-"`
 }
 
 // Render returns the source text represented by this Branch
@@ -35,9 +44,125 @@ func (a *Action) Render() (source string) {
 // Variables present
 func (a *Action) WalkVariables(fn VariablesWalkFn) (stopped bool) {
 	for _, pipeline := range a.Pipelines {
-		if stopped = pipeline.WalkVariables(fn); !stopped {
+		if stopped = pipeline.WalkVariables(fn); stopped {
 			return
 		}
 	}
 	return
 }
+
+// offset updates the positions of this Action, and all of its child nodes,
+// to be relative to the given base position
+func (a *Action) offset(base lexer.Position) {
+	a.Pos, a.EndPos = base.Add(a.Pos), base.Add(a.EndPos)
+	for _, pipeline := range a.Pipelines {
+		pipeline.offset(base)
+	}
+}
+
+// fixSignedNumber corrects actions like `{{-3}}` where the dash is the sign of
+// a number and not a left trim marker, given the action source text and
+// before any offset is applied
+func (a *Action) fixSignedNumber(source string) {
+	if a.Open == nil || !strings.HasSuffix(*a.Open, "-") || len(a.Pipelines) == 0 || a.Pipelines[0] == nil || len(a.Pipelines[0].Root) == 0 {
+		return
+	}
+	v := a.Pipelines[0].Root[0]
+	if v.Int == nil && v.Float == nil && (v.Number == nil || strings.IndexAny(*v.Number, "+-") == 0) {
+		return
+	}
+	number := "-" + source[v.Pos.Offset:v.EndPos.Offset]
+	open := strings.TrimSuffix(*a.Open, "-")
+	a.Open = &open
+	v.Int, v.Float, v.Number = nil, nil, &number
+	v.Pos.Offset -= 1
+	v.Pos.Column -= 1
+	a.Pipelines[0].Pos = v.Pos
+}
+
+// captureRaw sets the Raw source text of all literal Variables, given the
+// action source text and before any offset is applied
+func (a *Action) captureRaw(source string) {
+	a.WalkVariables(func(variables *Variables) (stop bool) {
+		for _, v := range *variables {
+			if v.String != nil || v.Literal != nil || v.Rune != nil || v.Float != nil || v.Int != nil {
+				v.Raw = source[v.Pos.Offset:v.EndPos.Offset]
+			}
+		}
+		return
+	})
+}
+
+// markActionComment sets the ActionComment flag on the comment Variable of an
+// Action which is a text/template comment: the comment must start right after
+// the opening delimiter, or after the trim marker and a single space, and end
+// right before the closing delimiter, or before a single space and the trim
+// marker. Like text/template, `{{/* c */}}` and `{{- /* c */ -}}` are action
+// comments while `{{ /* c */ }}` is not
+func (a *Action) markActionComment() {
+	if len(a.Pipelines) != 1 || a.Pipelines[0] == nil || a.Pipelines[0].Pipe != nil {
+		return
+	}
+	var comment *Variable
+	for _, v := range a.Pipelines[0].Root {
+		if v.Space != nil {
+			continue
+		} else if v.Comment == nil || comment != nil {
+			return
+		}
+		comment = v
+	}
+	if comment == nil || a.Open == nil || a.Close == nil {
+		return
+	}
+	start := a.Pos.Offset + len(*a.Open)
+	if strings.HasSuffix(*a.Open, "-") {
+		start += 1
+	}
+	end := a.EndPos.Offset - len(*a.Close)
+	if strings.HasPrefix(*a.Close, "-") {
+		end -= 1
+	}
+	if comment.Pos.Offset == start && comment.EndPos.Offset == end {
+		comment.ActionComment = true
+	}
+}
+
+// Control returns the control structure keyword starting this Action, or an
+// empty string if this Action is not a control structure. The keywords
+// returned are: "if", "range", "with", "define", "block", "else", "else if",
+// "else with", "end", "template", "break" and "continue"
+func (a *Action) Control() (keyword string) {
+	if len(a.Pipelines) == 0 || a.Pipelines[0] == nil {
+		return
+	}
+	var significant Variables
+	for _, v := range a.Pipelines[0].Root {
+		if v.Space == nil && v.Comment == nil {
+			significant = append(significant, v)
+			if len(significant) == 2 {
+				break
+			}
+		}
+	}
+	if len(significant) == 0 {
+		return
+	} else if significant[0].Range != nil {
+		return "range"
+	} else if significant[0].Ident == nil {
+		return
+	}
+	switch ident := *significant[0].Ident; ident {
+	case "if", "range", "with", "define", "block", "end", "template", "break", "continue":
+		return ident
+	case "else":
+		keyword = ident
+		if len(significant) > 1 && significant[1].Ident != nil {
+			switch *significant[1].Ident {
+			case "if", "with":
+				keyword += " " + *significant[1].Ident
+			}
+		}
+	}
+	return
+}
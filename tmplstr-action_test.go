@@ -0,0 +1,60 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAction(t *testing.T) {
+	Convey("WalkVariables", t, func() {
+		first, err := ParseTemplate("walk-variables", `{{ one two }}`)
+		So(err, ShouldBeNil)
+		second, err := ParseTemplate("walk-variables", `{{ many more }}`)
+		So(err, ShouldBeNil)
+		action := &Action{Pipelines: Pipelines{first[0].Action.Pipelines[0], second[0].Action.Pipelines[0]}}
+
+		var idents []string
+		walk := func(stopAt string) (stopped bool) {
+			idents = nil
+			return action.WalkVariables(func(variables *Variables) (stop bool) {
+				for _, v := range *variables {
+					if v.Ident != nil {
+						idents = append(idents, *v.Ident)
+						stop = stop || *v.Ident == stopAt
+					}
+				}
+				return
+			})
+		}
+
+		Convey("continues through all pipelines", func() {
+			So(walk(""), ShouldBeFalse)
+			So(idents, ShouldEqual, []string{"one", "two", "many", "more"})
+		})
+
+		Convey("stops within the first pipeline", func() {
+			So(walk("one"), ShouldBeTrue)
+			So(idents, ShouldEqual, []string{"one", "two"})
+		})
+
+		Convey("stops within the last pipeline", func() {
+			So(walk("more"), ShouldBeTrue)
+			So(idents, ShouldEqual, []string{"one", "two", "many", "more"})
+		})
+	})
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"sort"
+)
+
+// CommentBinding associates a template comment with the template node it
+// describes
+//
+// Inline comments, like the one in `{{ _ "key" /* context */ }}`, are bound
+// to the nearest preceding Variable within the same command: the Target is
+// the "key" String and the Call is the `_` Ident. Comment-only actions, like
+// `{{/* context */}}`, are Leading comments bound to the next Action which
+// is not itself a comment-only action
+type CommentBinding struct {
+	// Comment is the Comment Variable
+	Comment *Variable `json:"comment"`
+	// Text is the comment text, without the comment markers and surrounding
+	// whitespace
+	Text string `json:"text"`
+	// Leading is true for comment-only actions
+	Leading bool `json:"leading,omitempty"`
+	// Action is the Action containing an inline comment, or the next Action
+	// of a Leading comment (nil when there is no next Action)
+	Action *Action `json:"-"`
+	// Target is the nearest significant Variable preceding an inline comment
+	// within the same command, nil for Leading comments and inline comments
+	// with nothing preceding them within the command
+	Target *Variable `json:"-"`
+	// Call is the function or method called by the command containing an
+	// inline comment, nil when the command is not a call (such as `.Field`
+	// on its own) or for Leading comments
+	Call *Variable `json:"-"`
+}
+
+// BindComments returns the CommentBinding of every comment within the given
+// Tree, in source order
+func BindComments(tree Tree) (bindings []*CommentBinding) {
+	var leading []*CommentBinding
+	for _, branch := range tree {
+		if branch.Action == nil || len(branch.Action.Pipelines) == 0 {
+			continue
+		}
+		action := branch.Action
+		if comment := action.actionComment(); comment != nil {
+			cb := &CommentBinding{Comment: comment, Text: commentText(*comment.Comment), Leading: true}
+			leading = append(leading, cb)
+			bindings = append(bindings, cb)
+			continue
+		}
+		for _, cb := range leading {
+			cb.Action = action
+		}
+		leading = nil
+
+		walkCommands(action.Pipelines[0], action.Statement(), func(idx int, root, command Variables) {
+			var call *Variable
+			if len(command) > 0 && command[0].Ident != nil {
+				call = command[0]
+			}
+			var target *Variable
+			var inCommand bool
+			for _, v := range root {
+				switch {
+				case len(command) > 0 && v == command[0]:
+					inCommand, target = true, v
+				case v.Comment != nil:
+					cb := &CommentBinding{Comment: v, Text: commentText(*v.Comment), Action: action}
+					if inCommand {
+						cb.Target, cb.Call = target, call
+					}
+					bindings = append(bindings, cb)
+				case v.Space == nil:
+					target = v
+				}
+			}
+		})
+	}
+
+	sort.SliceStable(bindings, func(i, j int) bool {
+		return bindings[i].Comment.Pos.Offset < bindings[j].Comment.Pos.Offset
+	})
+	return
+}
+
+// actionComment returns the Comment Variable of comment-only actions
+func (a *Action) actionComment() (comment *Variable) {
+	for _, pipeline := range a.Pipelines {
+		for _, v := range pipeline.Root {
+			if v.ActionComment {
+				return v
+			}
+		}
+	}
+	return
+}
+
+// walkCommands calls fn with the index and Root of each Pipeline in the given
+// chain, along with the corresponding command from the given Statement (which
+// does not include any control keyword or declarations), descending into all
+// nested Groupings first
+func walkCommands(pipeline *Pipeline, s *Statement, fn func(idx int, root, command Variables)) {
+	for idx, pipe := 0, pipeline; pipe != nil; idx, pipe = idx+1, pipe.Pipe {
+		for _, v := range pipe.Root {
+			if v.Grouping != nil && v.Grouping.Group != nil {
+				walkCommands(v.Grouping.Group, v.Grouping.Statement(), fn)
+			}
+		}
+		var command Variables
+		if idx < len(s.Commands) {
+			command = s.Commands[idx]
+		}
+		fn(idx, pipe.Root, command)
+	}
+}
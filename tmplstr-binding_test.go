@@ -0,0 +1,90 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBindComments(t *testing.T) {
+
+	bind := func(input string) (Tree, []*CommentBinding) {
+		tree, err := ParseTemplate("binding.tmpl", input)
+		So(err, ShouldBeNil)
+		return tree, BindComments(tree)
+	}
+
+	Convey("inline comments", t, func() {
+		tree, bindings := bind(`{{ _ "key" /* context */ $v /* value */ | other /* piped */ }}`)
+		So(bindings, ShouldHaveLength, 3)
+		root := tree[0].Action.Pipelines[0].Root
+
+		So(bindings[0].Text, ShouldEqual, "context")
+		So(bindings[0].Leading, ShouldBeFalse)
+		So(bindings[0].Action, ShouldEqual, tree[0].Action)
+		So(bindings[0].Target, ShouldEqual, root[3])
+		So(bindings[0].Call, ShouldEqual, root[1])
+
+		So(bindings[1].Text, ShouldEqual, "value")
+		So(*bindings[1].Target.Keyword, ShouldEqual, "$v")
+		So(bindings[1].Call, ShouldEqual, root[1])
+
+		So(bindings[2].Text, ShouldEqual, "piped")
+		So(*bindings[2].Target.Ident, ShouldEqual, "other")
+		So(bindings[2].Call, ShouldEqual, bindings[2].Target)
+	})
+
+	Convey("groupings and keywords", t, func() {
+		_, bindings := bind(`{{ if /* keyword */ eq (_ "nested" /* inner */) .x /* outer */ }}{{ end }}`)
+		So(bindings, ShouldHaveLength, 3)
+		So(bindings[0].Text, ShouldEqual, "keyword")
+		So(bindings[0].Target, ShouldBeNil)
+		So(bindings[0].Call, ShouldBeNil)
+		So(bindings[1].Text, ShouldEqual, "inner")
+		So(*bindings[1].Target.String, ShouldEqual, "nested")
+		So(*bindings[1].Call.Ident, ShouldEqual, "_")
+		So(bindings[2].Text, ShouldEqual, "outer")
+		So(*bindings[2].Target.Keyword, ShouldEqual, ".x")
+		So(*bindings[2].Call.Ident, ShouldEqual, "eq")
+
+		_, bindings = bind(`{{ .Field /* not a call */ }}`)
+		So(bindings, ShouldHaveLength, 1)
+		So(*bindings[0].Target.Keyword, ShouldEqual, ".Field")
+		So(bindings[0].Call, ShouldBeNil)
+	})
+
+	Convey("leading comments", t, func() {
+		tree, bindings := bind("{{/* first */}}\n{{- /* second\n  line */ -}}\ntext {{ _ \"key\" }}{{/* dangling */}}")
+		So(bindings, ShouldHaveLength, 3)
+		So(bindings[0].Leading, ShouldBeTrue)
+		So(bindings[0].Text, ShouldEqual, "first")
+		So(bindings[0].Action, ShouldEqual, tree[4].Action)
+		So(bindings[0].Target, ShouldBeNil)
+		So(bindings[1].Text, ShouldEqual, "second\n  line")
+		So(bindings[1].Action, ShouldEqual, tree[4].Action)
+		So(bindings[2].Action, ShouldBeNil)
+	})
+
+	Convey("ExtractMessages translator notes", t, func() {
+		tree, _ := bind(`{{/* page title */}}{{ _ "Home" }}{{/* ignored */}}{{ _ "About" /* inline wins */ }}{{ __ "one" "many" /* plural */ .N }}`)
+		messages := ExtractMessages(tree)
+		So(messages, ShouldHaveLength, 3)
+		So(messages[0].Comment, ShouldEqual, "page title")
+		So(messages[1].Comment, ShouldEqual, "inline wins")
+		So(messages[2].Comment, ShouldEqual, "plural")
+	})
+}
@@ -0,0 +1,218 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"fmt"
+)
+
+// Node is a single entry within a BlockTree and is either a Branch which does
+// not open a control structure or a Block which does
+type Node struct {
+	Branch *Branch `json:"branch,omitempty"`
+	Block  *Block  `json:"block,omitempty"`
+}
+
+// Render returns the source text represented by this Node
+func (n *Node) Render() (source string) {
+	if n.Block != nil {
+		return n.Block.Render()
+	} else if n.Branch != nil {
+		return n.Branch.Render()
+	}
+	return
+}
+
+// Block represents a control structure, from the opening Action to the
+// matching {{end}} Action, including any {{else}} clauses
+//
+// The control structures recognized are: if, range, with, define and block
+type Block struct {
+	Keyword string    `json:"keyword"`
+	Open    *Branch   `json:"open"`
+	Body    BlockTree `json:"body,omitempty"`
+	Else    []*Else   `json:"else,omitempty"`
+	End     *Branch   `json:"end"`
+}
+
+// Render returns the source text represented by this Block
+func (b *Block) Render() (source string) {
+	if b.Open != nil {
+		source += b.Open.Render()
+	}
+	source += b.Body.Render()
+	for _, clause := range b.Else {
+		source += clause.Render()
+	}
+	if b.End != nil {
+		source += b.End.Render()
+	}
+	return
+}
+
+// Else represents a single {{else}}, {{else if}} or {{else with}} clause of
+// a Block
+type Else struct {
+	Keyword string    `json:"keyword"`
+	Open    *Branch   `json:"open"`
+	Body    BlockTree `json:"body,omitempty"`
+}
+
+// Render returns the source text represented by this Else clause
+func (e *Else) Render() (source string) {
+	if e.Open != nil {
+		source += e.Open.Render()
+	}
+	source += e.Body.Render()
+	return
+}
+
+// BlockTree is a list of Node instances and is the nested counterpart to the
+// flat Tree returned by ParseTemplate
+type BlockTree []*Node
+
+// Render returns the source text represented by this BlockTree
+func (bt BlockTree) Render() (source string) {
+	for _, node := range bt {
+		source += node.Render()
+	}
+	return
+}
+
+// Flatten returns the flat Tree represented by this BlockTree
+func (bt BlockTree) Flatten() (tree Tree) {
+	for _, node := range bt {
+		if node.Block != nil {
+			tree = append(tree, node.Block.Open)
+			tree = append(tree, node.Block.Body.Flatten()...)
+			for _, clause := range node.Block.Else {
+				tree = append(tree, clause.Open)
+				tree = append(tree, clause.Body.Flatten()...)
+			}
+			tree = append(tree, node.Block.End)
+		} else if node.Branch != nil {
+			tree = append(tree, node.Branch)
+		}
+	}
+	return
+}
+
+// BlockError describes a control structure mismatch found while folding a
+// Tree into a BlockTree
+type BlockError struct {
+	// Opener is the Branch which opened the control structure, nil when the
+	// Found Branch has no opener at all
+	Opener *Branch
+	// OpenerIndex is the index of Opener within the Tree, -1 when Opener is
+	// nil
+	OpenerIndex int
+	// Found is the Branch where the mismatch was detected, nil when the end
+	// of the Tree was reached without finding an {{end}}
+	Found *Branch
+	// FoundIndex is the index of Found within the Tree, -1 when Found is nil
+	FoundIndex int
+	// Message describes the mismatch
+	Message string
+}
+
+func (e *BlockError) Error() (message string) {
+	message = e.Message
+	if e.Opener != nil {
+		message += fmt.Sprintf("; opened by %q at %v", e.Opener.Render(), e.Opener.Pos)
+	}
+	if e.Found != nil {
+		message += fmt.Sprintf("; found %q at %v", e.Found.Render(), e.Found.Pos)
+	} else {
+		message += "; found end of input"
+	}
+	return
+}
+
+type blockFrame struct {
+	block *Block
+	index int
+	body  *BlockTree
+	final bool // a plain {{else}} clause has been seen
+}
+
+func (t Tree) structure() (blocks BlockTree, err error) {
+	var stack []*blockFrame
+	current := &blocks
+
+	for idx, branch := range t {
+		var keyword string
+		if branch.Action != nil {
+			keyword = branch.Action.Control()
+		}
+
+		switch keyword {
+
+		case "if", "range", "with", "define", "block":
+			block := &Block{Keyword: keyword, Open: branch}
+			*current = append(*current, &Node{Block: block})
+			stack = append(stack, &blockFrame{block: block, index: idx, body: &block.Body})
+			current = &block.Body
+
+		case "else", "else if", "else with":
+			if len(stack) == 0 {
+				err = &BlockError{OpenerIndex: -1, Found: branch, FoundIndex: idx, Message: "unexpected {{" + keyword + "}}"}
+				return
+			}
+			frame := stack[len(stack)-1]
+			switch {
+			case frame.block.Keyword == "define" || frame.block.Keyword == "block":
+				err = &BlockError{Opener: frame.block.Open, OpenerIndex: frame.index, Found: branch, FoundIndex: idx, Message: "{{" + frame.block.Keyword + "}} does not support {{" + keyword + "}}"}
+				return
+			case keyword != "else" && keyword != "else "+frame.block.Keyword:
+				err = &BlockError{Opener: frame.block.Open, OpenerIndex: frame.index, Found: branch, FoundIndex: idx, Message: "{{" + frame.block.Keyword + "}} does not support {{" + keyword + "}}"}
+				return
+			case frame.final:
+				err = &BlockError{Opener: frame.block.Open, OpenerIndex: frame.index, Found: branch, FoundIndex: idx, Message: "unexpected {{" + keyword + "}} after {{else}}"}
+				return
+			}
+			frame.final = keyword == "else"
+			clause := &Else{Keyword: keyword, Open: branch}
+			frame.block.Else = append(frame.block.Else, clause)
+			frame.body = &clause.Body
+			current = frame.body
+
+		case "end":
+			if len(stack) == 0 {
+				err = &BlockError{OpenerIndex: -1, Found: branch, FoundIndex: idx, Message: "unexpected {{end}}"}
+				return
+			}
+			frame := stack[len(stack)-1]
+			frame.block.End = branch
+			stack = stack[:len(stack)-1]
+			if len(stack) > 0 {
+				current = stack[len(stack)-1].body
+			} else {
+				current = &blocks
+			}
+
+		default:
+			*current = append(*current, &Node{Branch: branch})
+
+		}
+	}
+
+	if len(stack) > 0 {
+		frame := stack[len(stack)-1]
+		err = &BlockError{Opener: frame.block.Open, OpenerIndex: frame.index, FoundIndex: -1, Message: "missing {{end}} for {{" + frame.block.Keyword + "}}"}
+		return
+	}
+
+	return
+}
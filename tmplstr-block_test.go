@@ -0,0 +1,123 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBlockTree(t *testing.T) {
+
+	Convey("Action.Control", t, func() {
+		for input, keyword := range map[string]string{
+			`{{ pipeline }}`:            "",
+			`{{/* comment */}}`:         "",
+			`{{- if pipeline }}`:        "if",
+			`{{ /* note */ with .x }}`:  "with",
+			`{{range $i, $e := .x}}`:    "range",
+			`{{ else }}`:                "else",
+			`{{ else if pipeline }}`:    "else if",
+			`{{ else with pipeline }}`:  "else with",
+			`{{- end -}}`:               "end",
+			`{{ template "name" .x }}`:  "template",
+			`{{ define "name" }}`:       "define",
+			`{{ block "name" .x }}`:     "block",
+			`{{ $v := pipeline }}`:      "",
+			`{{ else pipeline }}`:       "else",
+			`{{ continue }}{{ break }}`: "continue",
+		} {
+			tree, err := ParseTemplate("control.tmpl", input)
+			So(err, ShouldBeNil)
+			So(tree[0].Action.Control(), ShouldEqual, keyword)
+		}
+		So((&Action{}).Control(), ShouldEqual, "")
+	})
+
+	Convey("Tree.Structure", t, func() {
+
+		Convey("nested if else chain", func() {
+			input := `a{{if .x}}b{{else if .y}}c{{range .z}}d{{end}}{{else}}e{{end}}f`
+			tree, err := ParseTemplate("structure.tmpl", input)
+			So(err, ShouldBeNil)
+			blocks, err := tree.Structure()
+			So(err, ShouldBeNil)
+			So(blocks, ShouldHaveLength, 3)
+			So(*blocks[0].Branch.Text, ShouldEqual, "a")
+			So(*blocks[2].Branch.Text, ShouldEqual, "f")
+			block := blocks[1].Block
+			So(block, ShouldNotBeNil)
+			So(block.Keyword, ShouldEqual, "if")
+			So(block.Body.Render(), ShouldEqual, "b")
+			So(block.Else, ShouldHaveLength, 2)
+			So(block.Else[0].Keyword, ShouldEqual, "else if")
+			So(block.Else[0].Body, ShouldHaveLength, 2)
+			So(block.Else[0].Body[1].Block.Keyword, ShouldEqual, "range")
+			So(block.Else[0].Body[1].Block.Body.Render(), ShouldEqual, "d")
+			So(block.Else[1].Keyword, ShouldEqual, "else")
+			So(block.End.Render(), ShouldEqual, "{{end}}")
+			So(blocks.Render(), ShouldEqual, input)
+			So(blocks.Flatten(), ShouldEqual, tree)
+		})
+
+		Convey("define, block and with", func() {
+			input := `{{define "a"}}{{with .x}}{{.y}}{{else with .y}}y{{end}}{{end}}{{block "b" .x}}b{{end}}`
+			tree, err := ParseTemplate("structure.tmpl", input)
+			So(err, ShouldBeNil)
+			blocks, err := tree.Structure()
+			So(err, ShouldBeNil)
+			So(blocks, ShouldHaveLength, 2)
+			So(blocks[0].Block.Keyword, ShouldEqual, "define")
+			So(blocks[0].Block.Body[0].Block.Keyword, ShouldEqual, "with")
+			So(blocks[0].Block.Body[0].Block.Else[0].Keyword, ShouldEqual, "else with")
+			So(blocks[1].Block.Keyword, ShouldEqual, "block")
+			So(blocks.Render(), ShouldEqual, input)
+		})
+
+		Convey("errors", func() {
+			for input, expect := range map[string]struct {
+				message string
+				opener  int
+				found   int
+			}{
+				`{{if .x}}body`:                          {"missing {{end}} for {{if}}", 0, -1},
+				`text{{end}}`:                            {"unexpected {{end}}", -1, 1},
+				`{{else}}`:                               {"unexpected {{else}}", -1, 0},
+				`{{define "x"}}{{else}}{{end}}`:          {"{{define}} does not support {{else}}", 0, 1},
+				`{{range .x}}{{else if .y}}{{end}}`:      {"{{range}} does not support {{else if}}", 0, 1},
+				`{{if .x}}{{else}}{{else if .y}}{{end}}`: {"unexpected {{else if}} after {{else}}", 0, 2},
+			} {
+				tree, err := ParseTemplate("structure.tmpl", input)
+				So(err, ShouldBeNil)
+				blocks, err := tree.Structure()
+				So(blocks, ShouldBeNil)
+				So(err, ShouldNotBeNil)
+				be, ok := err.(*BlockError)
+				So(ok, ShouldBeTrue)
+				So(be.Message, ShouldEqual, expect.message)
+				So(be.OpenerIndex, ShouldEqual, expect.opener)
+				So(be.FoundIndex, ShouldEqual, expect.found)
+				So(be.Error(), ShouldStartWith, expect.message)
+			}
+		})
+
+		Convey("Node.Render", func() {
+			So((&Node{}).Render(), ShouldEqual, "")
+			So((&Block{}).Render(), ShouldEqual, "")
+			So((&Else{}).Render(), ShouldEqual, "")
+		})
+	})
+}
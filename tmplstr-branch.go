@@ -14,9 +14,19 @@
 
 package tmplstr
 
+import (
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+// Branch is a single Text or Action entry within a Tree. Trees produced by
+// ParseTemplateTolerant may also contain Invalid branches
 type Branch struct {
-	Action *Action `parser:"( @@ "        json:"action,omitempty"`
-	Text   *string `parser:"  | @Text )"  json:"text,omitempty"`
+	Action  *Action  `parser:"( @@ "        json:"action,omitempty"`
+	Text    *string  `parser:"  | @Text )"  json:"text,omitempty"`
+	Invalid *Invalid `parser:""             json:"invalid,omitempty"`
+
+	Pos    lexer.Position `parser:"" json:"-"`
+	EndPos lexer.Position `parser:"" json:"-"`
 }
 
 // Render returns the source text represented by this Branch
@@ -25,6 +35,16 @@ func (b *Branch) Render() (source string) {
 		return *b.Text
 	} else if b.Action != nil {
 		return b.Action.Render()
+	} else if b.Invalid != nil {
+		return b.Invalid.Source
 	}
 	return
 }
+
+// Invalid represents an action which failed to parse
+type Invalid struct {
+	// Source is the complete source text of the action
+	Source string `json:"source"`
+	// Err is the reason the action failed to parse
+	Err *ParseError `json:"error"`
+}
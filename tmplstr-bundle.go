@@ -0,0 +1,205 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/participle/v2/lexer"
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message/catalog"
+)
+
+// Bundle is the de-duplicated list of messages found within one or more
+// Trees, used to export message catalogs for golang.org/x/text and go-i18n
+type Bundle struct {
+	// Messages is the list of unique messages, sorted by ID
+	Messages []*BundleMessage `json:"messages"`
+	// Conflicts is the list of message IDs used with different translator
+	// comments, sorted by ID
+	Conflicts []*MessageConflict `json:"conflicts,omitempty"`
+}
+
+// BundleMessage is a single unique message within a Bundle
+type BundleMessage struct {
+	// ID is the message text
+	ID string `json:"id"`
+	// Plural is the plural message text, if any usage had one
+	Plural string `json:"plural,omitempty"`
+	// Comment is the first translator comment found
+	Comment string `json:"comment,omitempty"`
+	// Positions are the positions of all usages of this message
+	Positions []lexer.Position `json:"positions"`
+}
+
+// MessageConflict describes a message ID used with different translator
+// comments
+type MessageConflict struct {
+	// ID is the message text
+	ID string `json:"id"`
+	// Comments are the distinct translator comments, in source order
+	Comments []string `json:"comments"`
+	// Positions are the positions of the usages with comments, in the same
+	// order as Comments
+	Positions []lexer.Position `json:"positions"`
+}
+
+// String returns a human-readable description of this MessageConflict
+func (c *MessageConflict) String() string {
+	var details []string
+	for idx, comment := range c.Comments {
+		details = append(details, fmt.Sprintf("%v: %q", c.Positions[idx], comment))
+	}
+	return fmt.Sprintf("message %q has conflicting comments: %s", c.ID, strings.Join(details, ", "))
+}
+
+// NewBundle returns a new Bundle of the given messages, as returned by
+// ExtractMessages for any number of Trees
+func NewBundle(messages []Message) (b *Bundle) {
+	b = &Bundle{}
+	lookup := make(map[string]*BundleMessage)
+	conflicts := make(map[string]*MessageConflict)
+	for _, m := range messages {
+		bm, present := lookup[m.ID]
+		if !present {
+			bm = &BundleMessage{ID: m.ID}
+			lookup[m.ID] = bm
+			b.Messages = append(b.Messages, bm)
+		}
+		bm.Positions = append(bm.Positions, m.Pos)
+		if bm.Plural == "" {
+			bm.Plural = m.Plural
+		}
+		if m.Comment == "" {
+			continue
+		}
+		mc, present := conflicts[m.ID]
+		if !present {
+			mc = &MessageConflict{ID: m.ID}
+			conflicts[m.ID] = mc
+		}
+		if appended := appendUnique(mc.Comments, m.Comment); len(appended) > len(mc.Comments) {
+			mc.Comments = appended
+			mc.Positions = append(mc.Positions, m.Pos)
+		}
+		if bm.Comment == "" {
+			bm.Comment = m.Comment
+		}
+	}
+
+	sort.SliceStable(b.Messages, func(i, j int) bool {
+		return b.Messages[i].ID < b.Messages[j].ID
+	})
+	for _, bm := range b.Messages {
+		if mc, ok := conflicts[bm.ID]; ok && len(mc.Comments) > 1 {
+			b.Conflicts = append(b.Conflicts, mc)
+		}
+	}
+	return
+}
+
+// Catalog returns a new golang.org/x/text catalog.Builder with all of the
+// Bundle messages set for the given language, using the message text as
+// both the key and the translation. Plural messages select between the
+// singular and plural text using the first argument
+func (b *Bundle) Catalog(tag language.Tag) (builder *catalog.Builder, err error) {
+	builder = catalog.NewBuilder(catalog.Fallback(tag))
+	for _, bm := range b.Messages {
+		if bm.Plural != "" {
+			err = builder.Set(tag, bm.ID, plural.Selectf(1, "", plural.One, bm.ID, plural.Other, bm.Plural))
+		} else {
+			err = builder.SetString(tag, bm.ID, bm.ID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("message %q: %w", bm.ID, err)
+		}
+	}
+	return
+}
+
+// JSON returns a go-i18n message file, in JSON format, of all the Bundle
+// messages. Each message is an object with the "description" (translator
+// comment), "one" (singular text of plural messages) and "other" (message
+// text) fields
+func (b *Bundle) JSON() (data []byte, err error) {
+	messages := make(map[string]map[string]string)
+	for _, bm := range b.Messages {
+		messages[bm.ID] = bm.i18n()
+	}
+	return json.MarshalIndent(messages, "", "  ")
+}
+
+// TOML returns a go-i18n message file, in TOML format, of all the Bundle
+// messages. See Bundle.JSON for the fields of each message
+func (b *Bundle) TOML() (data []byte) {
+	var sb strings.Builder
+	for idx, bm := range b.Messages {
+		if idx > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString("[" + tomlQuote(bm.ID) + "]\n")
+		fields := bm.i18n()
+		for _, key := range []string{"description", "one", "other"} {
+			if value, ok := fields[key]; ok {
+				sb.WriteString(key + " = " + tomlQuote(value) + "\n")
+			}
+		}
+	}
+	return []byte(sb.String())
+}
+
+// i18n returns the go-i18n message fields of this BundleMessage
+func (bm *BundleMessage) i18n() (fields map[string]string) {
+	fields = map[string]string{"other": bm.ID}
+	if bm.Comment != "" {
+		fields["description"] = bm.Comment
+	}
+	if bm.Plural != "" {
+		fields["one"], fields["other"] = bm.ID, bm.Plural
+	}
+	return
+}
+
+// tomlQuote returns the given value as a TOML basic string
+func tomlQuote(value string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range value {
+		switch r {
+		case '\\':
+			sb.WriteString(`\\`)
+		case '"':
+			sb.WriteString(`\"`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\r':
+			sb.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				sb.WriteString(fmt.Sprintf(`\u%04X`, r))
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+func TestBundle(t *testing.T) {
+
+	tBundle := func() *Bundle {
+		var messages []Message
+		for _, input := range [][2]string{
+			{"page.tmpl", "{{ _ \"Hello %s\" /* greeting */ .Name }}\n{{ __ \"%d item\" \"%d items\" .Count }}"},
+			{"other.tmpl", "{{ _ \"Hello %s\" /* the user name */ .User }}{{ _ \"Hello %s\" /* greeting */ .User }}{{ _ \"Say \\\"hi\\\"\" }}"},
+		} {
+			tree, err := ParseTemplate(input[0], input[1])
+			So(err, ShouldBeNil)
+			messages = append(messages, ExtractMessages(tree)...)
+		}
+		return NewBundle(messages)
+	}
+
+	Convey("NewBundle", t, func() {
+		b := tBundle()
+		So(b.Messages, ShouldHaveLength, 3)
+		So(b.Messages[0].ID, ShouldEqual, "%d item")
+		So(b.Messages[0].Plural, ShouldEqual, "%d items")
+		So(b.Messages[1].ID, ShouldEqual, "Hello %s")
+		So(b.Messages[1].Comment, ShouldEqual, "greeting")
+		So(b.Messages[1].Positions, ShouldHaveLength, 3)
+		So(b.Messages[1].Positions[1].Filename, ShouldEqual, "other.tmpl")
+		So(b.Messages[2].ID, ShouldEqual, `Say "hi"`)
+
+		So(b.Conflicts, ShouldHaveLength, 1)
+		c := b.Conflicts[0]
+		So(c.ID, ShouldEqual, "Hello %s")
+		So(c.Comments, ShouldEqual, []string{"greeting", "the user name"})
+		So(c.Positions[1].Filename, ShouldEqual, "other.tmpl")
+		So(c.String(), ShouldEqual, `message "Hello %s" has conflicting comments: page.tmpl:1:6: "greeting", other.tmpl:1:6: "the user name"`)
+	})
+
+	Convey("Catalog", t, func() {
+		builder, err := tBundle().Catalog(language.English)
+		So(err, ShouldBeNil)
+		p := message.NewPrinter(language.English, message.Catalog(builder))
+		So(p.Sprintf("Hello %s", "World"), ShouldEqual, "Hello World")
+		So(p.Sprintf("%d item", 1), ShouldEqual, "1 item")
+		So(p.Sprintf("%d item", 3), ShouldEqual, "3 items")
+	})
+
+	Convey("JSON", t, func() {
+		data, err := tBundle().JSON()
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, `{
+  "%d item": {
+    "one": "%d item",
+    "other": "%d items"
+  },
+  "Hello %s": {
+    "description": "greeting",
+    "other": "Hello %s"
+  },
+  "Say \"hi\"": {
+    "other": "Say \"hi\""
+  }
+}`)
+	})
+
+	Convey("TOML", t, func() {
+		So(string(tBundle().TOML()), ShouldEqual, `["%d item"]
+one = "%d item"
+other = "%d items"
+
+["Hello %s"]
+description = "greeting"
+other = "Hello %s"
+
+["Say \"hi\""]
+other = "Say \"hi\""
+`)
+		So(tomlQuote("a\tb\x01"), ShouldEqual, `"a\tb\u0001"`)
+	})
+}
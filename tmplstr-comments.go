@@ -19,11 +19,12 @@ import (
 )
 
 // PruneTemplateComments is like RemoveTemplateComments with a very
-// fundamental difference, instead of using ScanCarve and ScanBothCarve,
+// fundamental difference, instead of using a simple text scanner,
 // PruneTemplateComments uses ParseTemplate, walks the Variables in the
-// Tree, setting all Variable.Comment values to nil and finally returning the
-// rendered results of the modified Tree. If PruneTemplateComments fails to
-// ParseTemplate, the error is returned and pruned is empty
+// Tree, setting all inline Variable.Comment values to nil (whole-action
+// comments and actions with nothing but comments are preserved) and finally
+// returning the rendered results of the modified Tree. If PruneTemplateComments fails to ParseTemplate, the error
+// is returned and pruned is empty
 //
 // RemoveTemplateComments is much faster at this task and PruneTemplateComments
 // is more-or-less an example of using ParseTemplate to modify the template
@@ -35,20 +36,38 @@ import (
 //	RemoveTemplateComments-4   	1000000000	         0.004838 ns/op
 //	(both implementations have 0 B/op and 0 allocs/op)
 func PruneTemplateComments(input string) (pruned string, err error) {
+	return gDefaultParser.PruneTemplateComments(input)
+}
+
+// PruneTemplateComments is the Parser equivalent of the package-level
+// PruneTemplateComments function, using this Parser's action delimiters
+func (p *Parser) PruneTemplateComments(input string) (pruned string, err error) {
 	var tree Tree
-	if tree, err = ParseTemplate("prune-template-comments.tmpl", input); err == nil {
+	if tree, err = p.ParseTemplate("prune-template-comments.tmpl", input); err == nil {
 		for _, branch := range tree {
 			if branch.Text != nil {
 				pruned += *branch.Text
 				continue
 			} else if branch.Action != nil {
+				// an action with nothing but comments is kept as-is, like
+				// RemoveTemplateComments, pruning would leave it empty
+				significant := branch.Action.WalkVariables(func(variables *Variables) (stop bool) {
+					for _, v := range *variables {
+						if v.Space == nil && v.Comment == nil {
+							return true
+						}
+					}
+					return
+				})
+				if !significant {
+					pruned += branch.Action.Render()
+					continue
+				}
 				branch.Action.WalkVariables(func(variables *Variables) (stop bool) {
-					if len(*variables) > 1 {
-						// more than just a valid template comment, prune...
-						for _, v := range *variables {
-							if v.Comment != nil {
-								v.Comment = nil
-							}
+					for _, v := range *variables {
+						if v.Comment != nil && !v.ActionComment {
+							// inline comment, prune...
+							v.Comment = nil
 						}
 					}
 					return
@@ -61,15 +80,23 @@ func PruneTemplateComments(input string) (pruned string, err error) {
 }
 
 // RemoveTemplateComments removes all C-style block comments from within
-// template pipelines, preserving escaped and quoted comments, using
-// [github.com/go-corelibs/strings] ScanCarve and ScanBothCarve making
-// RemoveTemplateComments very fast compared to PruneTemplateComments
+// template pipelines, preserving escaped and quoted comments along with
+// whole-action comments like `{{/* comment */}}`, using a simple text scanner
+// instead of the parser, making RemoveTemplateComments very fast compared to
+// PruneTemplateComments. Comments may span multiple lines
 func RemoveTemplateComments(input string) (cleaned string) {
+	return gDefaultParser.RemoveTemplateComments(input)
+}
+
+// RemoveTemplateComments is the Parser equivalent of the package-level
+// RemoveTemplateComments function, using this Parser's action delimiters
+func (p *Parser) RemoveTemplateComments(input string) (cleaned string) {
 
 	for temp := input[:]; ; {
-		if beforePipelines, pipelines, afterPipelines, foundPipelines := clStrings.ScanCarve(temp, "{{", "}}"); foundPipelines {
+		beforePipelines, pipelines, afterPipelines, foundPipelines, err := scanAction(temp, p.left, p.right)
+		if foundPipelines {
 			cleaned += beforePipelines
-			cleaned += "{{"
+			cleaned += p.left
 
 			var endingDashed bool
 			if last := len(pipelines) - 1; last > 0 {
@@ -83,19 +110,7 @@ func RemoveTemplateComments(input string) (cleaned string) {
 				}
 			}
 
-			var inner string
-			innerPipelines := pipelines[:]
-			for {
-				if beforeComment, _, afterComment, foundComment := clStrings.ScanBothCarve(innerPipelines, "/*", "*/"); foundComment {
-					inner += beforeComment
-					innerPipelines = afterComment
-					continue
-				} else {
-					inner += beforeComment
-				}
-				break
-			}
-			if clStrings.Empty(inner) {
+			if inner := removeComments(pipelines); clStrings.Empty(inner) {
 				cleaned += pipelines
 			} else {
 				cleaned += inner
@@ -104,12 +119,15 @@ func RemoveTemplateComments(input string) (cleaned string) {
 			if endingDashed {
 				cleaned += "-"
 			}
-			cleaned += "}}"
+			cleaned += p.right
 
 			temp = afterPipelines
 			continue
-		} else {
-			cleaned += beforePipelines
+		}
+		cleaned += beforePipelines
+		if err != nil {
+			// keep the unterminated action as-is
+			cleaned += p.left + pipelines
 		}
 		break
 	}
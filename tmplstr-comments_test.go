@@ -59,6 +59,24 @@ func TestTemplating(t *testing.T) {
 				input:  `before {{/* comment */}} after`,
 				output: `before {{/* comment */}} after`,
 			},
+			{
+				label:  "actual trimmed",
+				err:    false,
+				input:  `before {{- /* comment */ -}} after`,
+				output: `before {{- /* comment */ -}} after`,
+			},
+			{
+				label:  "actual multi-line",
+				err:    false,
+				input:  "before {{/*\n  it's a comment\n  with }} inside\n*/}} after",
+				output: "before {{/*\n  it's a comment\n  with }} inside\n*/}} after",
+			},
+			{
+				label:  "inline multi-line",
+				err:    false,
+				input:  "before {{- _ \"thing\" /*\n  it's a comment\n*/ .Arg -}} after",
+				output: "before {{- _ \"thing\"  .Arg -}} after",
+			},
 		}
 
 		for _, check := range checks {
@@ -111,6 +129,35 @@ func TestTemplating(t *testing.T) {
 						in:  `before {{-/* comment */-}} after`,
 						out: `before {{-/* comment */-}} after`,
 					},
+					{
+						in:  `before {{- /* comment */ -}} after`,
+						out: `before {{- /* comment */ -}} after`,
+					},
+					{
+						in:  "before {{/*\n  it's a multi-line\n  comment with }} inside\n*/}} after",
+						out: "before {{/*\n  it's a multi-line\n  comment with }} inside\n*/}} after",
+					},
+					{
+						in:  "before {{- /*\n  multi-line\n*/ -}} after",
+						out: "before {{- /*\n  multi-line\n*/ -}} after",
+					},
+				},
+			},
+			{
+				label: "multi-line things",
+				cases: []tSimpleInputOutput{
+					{
+						in:  "before {{ _ \"thing\" /*\n  it's a comment\n*/ $var }} after",
+						out: "before {{ _ \"thing\"  $var }} after",
+					},
+					{
+						in:  "before {{- _ \"thing\" /*\n  comment\n*/ -}} after",
+						out: "before {{- _ \"thing\"  -}} after",
+					},
+					{
+						in:  "before {{ _ `raw /* not\na comment */` /* comment\n*/ }} after",
+						out: "before {{ _ `raw /* not\na comment */`  }} after",
+					},
 				},
 			},
 			{
@@ -150,6 +197,98 @@ func TestTemplating(t *testing.T) {
 
 }
 
+func TestTemplateComments(t *testing.T) {
+	Convey("ParseTemplate comments", t, func() {
+
+		Convey("multi-line action comment", func() {
+			input := "{{- /*\n  it's a comment\n*/ -}}"
+			tree, err := ParseTemplate("comments.tmpl", input)
+			So(err, ShouldBeNil)
+			So(tree, ShouldHaveLength, 1)
+			root := tree[0].Action.Pipelines[0].Root
+			So(root, ShouldHaveLength, 3)
+			So(*root[1].Comment, ShouldEqual, "/*\n  it's a comment\n*/")
+			So(root[1].ActionComment, ShouldBeTrue)
+			So(root[1].EndPos.Line, ShouldEqual, 3)
+			So(tree.Render(), ShouldEqual, input)
+		})
+
+		Convey("multi-line inline comment", func() {
+			input := "{{ _ \"key\" /*\n  note\n*/ .Arg }} after"
+			tree, err := ParseTemplate("comments.tmpl", input)
+			So(err, ShouldBeNil)
+			So(tree, ShouldHaveLength, 2)
+			root := tree[0].Action.Pipelines[0].Root
+			So(*root[5].Comment, ShouldEqual, "/*\n  note\n*/")
+			So(root[5].ActionComment, ShouldBeFalse)
+			So(*root[7].Keyword, ShouldEqual, ".Arg")
+			So(root[7].Pos.Line, ShouldEqual, 3)
+			So(*tree[1].Text, ShouldEqual, " after")
+			So(tree.Render(), ShouldEqual, input)
+		})
+
+		Convey("action comment spellings", func() {
+			actionComment := func(input string) bool {
+				tree, err := ParseTemplate("comments.tmpl", input)
+				So(err, ShouldBeNil)
+				So(tree.Render(), ShouldEqual, input)
+				for _, v := range tree[0].Action.Pipelines[0].Root {
+					if v.Comment != nil {
+						return v.ActionComment
+					}
+				}
+				return false
+			}
+			for _, input := range []string{
+				`{{/* c */}}`,
+				`{{- /* c */}}`,
+				`{{/* c */ -}}`,
+				`{{- /* c */ -}}`,
+				"{{-\t/* c */\n-}}",
+			} {
+				So(actionComment(input), ShouldBeTrue)
+				_, err := ToParseTree(tMustParse(input))
+				So(err, ShouldBeNil)
+			}
+			for _, input := range []string{
+				`{{ /* c */ }}`,
+				`{{ /* c */}}`,
+				`{{/* c */ }}`,
+				`{{-  /* c */ -}}`,
+				`{{- /* c */  -}}`,
+			} {
+				So(actionComment(input), ShouldBeFalse)
+				_, err := ToParseTree(tMustParse(input))
+				So(err, ShouldNotBeNil)
+			}
+
+			// actions with nothing but comments are never pruned
+			for _, input := range []string{
+				`{{/* c */}}{{ /* c */ }}`,
+				`{{-/* a */-}}`,
+				`{{/* a */ /* b */}}`,
+				`{{- /* a */ /* b */ -}}`,
+			} {
+				pruned, err := PruneTemplateComments(input)
+				So(err, ShouldBeNil)
+				So(pruned, ShouldEqual, input)
+				So(pruned, ShouldEqual, RemoveTemplateComments(input))
+			}
+			pruned, err := PruneTemplateComments(`{{- /* a */ .x /* b */ -}}`)
+			So(err, ShouldBeNil)
+			So(pruned, ShouldEqual, `{{-  .x  -}}`)
+		})
+
+		Convey("quoted delimiters", func() {
+			input := `{{ print "}}" '}' }} after`
+			tree, err := ParseTemplate("comments.tmpl", input)
+			So(err, ShouldBeNil)
+			So(tree, ShouldHaveLength, 2)
+			So(*tree[1].Text, ShouldEqual, " after")
+		})
+	})
+}
+
 func BenchmarkPruneTemplateComments(b *testing.B) {
 	for i := 0; i < 1000; i++ {
 		end := rand.Intn(gPruneTemplateCommentsTestingParagraphLen)
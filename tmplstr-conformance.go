@@ -0,0 +1,355 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template/parse"
+
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+// Difference is a single structural disagreement found by Compare
+type Difference struct {
+	// Pos is the position of the tmplstr node involved, when available
+	Pos lexer.Position
+	// Ours describes the tmplstr node, empty when missing from the Tree
+	Ours string
+	// Theirs describes the text/template/parse node, empty when missing from
+	// the parse.Tree
+	Theirs string
+}
+
+// String returns a human-readable description of this Difference
+func (d Difference) String() string {
+	switch {
+	case d.Ours == "":
+		return fmt.Sprintf("%v: missing %q", d.Pos, d.Theirs)
+	case d.Theirs == "":
+		return fmt.Sprintf("%v: unexpected %q", d.Pos, d.Ours)
+	}
+	return fmt.Sprintf("%v: %q != %q", d.Pos, d.Ours, d.Theirs)
+}
+
+// Compare reports the structural disagreements between the given Tree and
+// the text/template/parse Tree of the same source text. Control keywords,
+// declarations, pipelines, identifiers, operands and whole-action comments
+// are compared while Text content, whitespace and inline comments (which
+// text/template does not support) are not. Comments are only compared when
+// the stdTree was parsed with the parse.ParseComments mode
+//
+// The stdTree only contains the main template, so the bodies of {{define}}
+// actions are skipped and {{block}} actions are compared as {{template}}
+// actions. Use Compare on each of the associated parse.Tree instances to
+// check those
+func Compare(tree Tree, stdTree *parse.Tree) (differences []Difference) {
+	blocks, err := tree.Structure()
+	if err != nil {
+		var pos lexer.Position
+		if be, ok := err.(*BlockError); ok && be.Found != nil {
+			pos = be.Found.Pos
+		}
+		return []Difference{{Pos: pos, Ours: "error: " + err.Error()}}
+	}
+
+	var ours, theirs []cmpEvent
+	comments := stdTree != nil && stdTree.Mode&parse.ParseComments != 0
+	ours = cmpBlockTree(ours, blocks, comments)
+	if stdTree != nil && stdTree.Root != nil {
+		theirs = cmpStdNode(theirs, stdTree.Root)
+	}
+	return cmpDiff(ours, theirs)
+}
+
+type cmpEvent struct {
+	pos  lexer.Position
+	text string
+}
+
+func cmpBlockTree(events []cmpEvent, blocks BlockTree, comments bool) []cmpEvent {
+	for _, node := range blocks {
+		if node.Block != nil {
+			events = cmpBlock(events, node.Block, comments)
+		} else if node.Branch != nil && node.Branch.Action != nil {
+			a := node.Branch.Action
+			s := a.Statement()
+			switch s.Keyword {
+			case "":
+				if len(s.Commands) == 0 && len(s.Decl) == 0 {
+					if comments {
+						a.WalkVariables(func(variables *Variables) (stop bool) {
+							for _, v := range *variables {
+								if v.ActionComment {
+									events = append(events, cmpEvent{pos: v.Pos, text: "comment " + *v.Comment})
+								}
+							}
+							return
+						})
+					}
+					continue
+				}
+				events = append(events, cmpEvent{pos: a.Pos, text: "action " + cmpStatement(s)})
+			case "template":
+				events = append(events, cmpEvent{pos: a.Pos, text: "template " + strconv.Quote(s.NameValue()) + " " + cmpStatement(s)})
+			default:
+				events = append(events, cmpEvent{pos: a.Pos, text: s.Keyword})
+			}
+		}
+	}
+	return events
+}
+
+func cmpBlock(events []cmpEvent, block *Block, comments bool) []cmpEvent {
+	s := block.Open.Action.Statement()
+	switch block.Keyword {
+	case "define":
+		return events
+	case "block":
+		return append(events, cmpEvent{pos: block.Open.Pos, text: "template " + strconv.Quote(s.NameValue()) + " " + cmpStatement(s)})
+	}
+	events = append(events, cmpEvent{pos: block.Open.Pos, text: block.Keyword + " " + cmpStatement(s)})
+	events = cmpBlockTree(events, block.Body, comments)
+	var nested int
+	for _, clause := range block.Else {
+		events = append(events, cmpEvent{pos: clause.Open.Pos, text: "else"})
+		if clause.Keyword != "else" {
+			// else-if and else-with are nested blocks in text/template/parse
+			cs := clause.Open.Action.Statement()
+			keyword := strings.TrimPrefix(clause.Keyword, "else ")
+			events = append(events, cmpEvent{pos: clause.Open.Pos, text: keyword + " " + cmpStatement(cs)})
+			nested += 1
+		}
+		events = cmpBlockTree(events, clause.Body, comments)
+	}
+	for idx := 0; idx <= nested; idx++ {
+		events = append(events, cmpEvent{pos: block.End.Pos, text: "end"})
+	}
+	return events
+}
+
+func cmpStatement(s *Statement) (text string) {
+	if len(s.Decl) > 0 {
+		text += strings.Join(s.Decl, ", ")
+		if s.IsAssign {
+			text += " = "
+		} else {
+			text += " := "
+		}
+	}
+	for idx, command := range s.Commands {
+		if idx > 0 {
+			text += " | "
+		}
+		for jdx, v := range command {
+			if jdx > 0 {
+				text += " "
+			}
+			text += cmpVariable(v)
+		}
+	}
+	return
+}
+
+func cmpVariable(v *Variable) (text string) {
+	switch {
+	case v.Ident != nil:
+		return "ident:" + *v.Ident
+	case v.Keyword != nil && strings.HasPrefix(*v.Keyword, "$"):
+		return "var:" + *v.Keyword
+	case v.Keyword != nil:
+		return "field:" + *v.Keyword
+	case v.Dot != nil:
+		return "dot"
+	case v.Nil != nil:
+		return "nil"
+	case v.Bool != nil:
+		return "bool:" + strconv.FormatBool(bool(*v.Bool))
+	case v.String != nil:
+		return "string:" + strconv.Quote(*v.String)
+	case v.Literal != nil:
+		return "string:" + strconv.Quote(*v.Literal)
+	case v.Number != nil:
+		return "number:" + *v.Number
+	case v.Rune != nil, v.Int != nil, v.Float != nil:
+		return "number:" + v.Render()
+	case v.Grouping != nil:
+		text = "(" + cmpStatement(v.Grouping.Statement()) + ")"
+		if v.Grouping.Field != nil {
+			text += *v.Grouping.Field
+		}
+		return
+	}
+	return "unexpected:" + v.Render()
+}
+
+func cmpStdNode(events []cmpEvent, node parse.Node) []cmpEvent {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n != nil {
+			for _, child := range n.Nodes {
+				events = cmpStdNode(events, child)
+			}
+		}
+	case *parse.ActionNode:
+		events = append(events, cmpEvent{text: "action " + cmpStdPipe(n.Pipe)})
+	case *parse.CommentNode:
+		events = append(events, cmpEvent{text: "comment " + n.Text})
+	case *parse.IfNode:
+		events = cmpStdBranch(events, "if", &n.BranchNode)
+	case *parse.RangeNode:
+		events = cmpStdBranch(events, "range", &n.BranchNode)
+	case *parse.WithNode:
+		events = cmpStdBranch(events, "with", &n.BranchNode)
+	case *parse.TemplateNode:
+		events = append(events, cmpEvent{text: "template " + strconv.Quote(n.Name) + " " + cmpStdPipe(n.Pipe)})
+	case *parse.BreakNode:
+		events = append(events, cmpEvent{text: "break"})
+	case *parse.ContinueNode:
+		events = append(events, cmpEvent{text: "continue"})
+	case *parse.TextNode:
+	default:
+		events = append(events, cmpEvent{text: fmt.Sprintf("unexpected:%T", node)})
+	}
+	return events
+}
+
+func cmpStdBranch(events []cmpEvent, keyword string, n *parse.BranchNode) []cmpEvent {
+	events = append(events, cmpEvent{text: keyword + " " + cmpStdPipe(n.Pipe)})
+	events = cmpStdNode(events, n.List)
+	if n.ElseList != nil {
+		events = append(events, cmpEvent{text: "else"})
+		events = cmpStdNode(events, n.ElseList)
+	}
+	return append(events, cmpEvent{text: "end"})
+}
+
+func cmpStdPipe(pipe *parse.PipeNode) (text string) {
+	if pipe == nil {
+		return
+	}
+	if len(pipe.Decl) > 0 {
+		var names []string
+		for _, decl := range pipe.Decl {
+			names = append(names, strings.Join(decl.Ident, "."))
+		}
+		text += strings.Join(names, ", ")
+		if pipe.IsAssign {
+			text += " = "
+		} else {
+			text += " := "
+		}
+	}
+	for idx, cmd := range pipe.Cmds {
+		if idx > 0 {
+			text += " | "
+		}
+		for jdx, arg := range cmd.Args {
+			if jdx > 0 {
+				text += " "
+			}
+			text += cmpStdArg(arg)
+		}
+	}
+	return
+}
+
+func cmpStdArg(node parse.Node) string {
+	switch n := node.(type) {
+	case *parse.IdentifierNode:
+		return "ident:" + n.Ident
+	case *parse.VariableNode:
+		return "var:" + strings.Join(n.Ident, ".")
+	case *parse.FieldNode:
+		return "field:." + strings.Join(n.Ident, ".")
+	case *parse.DotNode:
+		return "dot"
+	case *parse.NilNode:
+		return "nil"
+	case *parse.BoolNode:
+		return "bool:" + strconv.FormatBool(n.True)
+	case *parse.StringNode:
+		return "string:" + strconv.Quote(n.Text)
+	case *parse.NumberNode:
+		return "number:" + n.Text
+	case *parse.PipeNode:
+		return "(" + cmpStdPipe(n) + ")"
+	case *parse.ChainNode:
+		return cmpStdArg(n.Node) + "." + strings.Join(n.Field, ".")
+	}
+	return fmt.Sprintf("unexpected:%T", node)
+}
+
+// cmpDiff aligns the two event lists using their longest common subsequence
+// and returns the unmatched events as Differences, pairing adjacent unmatched
+// events as changes
+func cmpDiff(ours, theirs []cmpEvent) (differences []Difference) {
+	lcs := make([][]int, len(ours)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(theirs)+1)
+	}
+	for i := len(ours) - 1; i >= 0; i-- {
+		for j := len(theirs) - 1; j >= 0; j-- {
+			if ours[i].text == theirs[j].text {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var removed, added []cmpEvent
+	flush := func(pos lexer.Position) {
+		for len(removed) > 0 || len(added) > 0 {
+			var d Difference
+			if len(removed) > 0 {
+				d.Pos, d.Ours = removed[0].pos, removed[0].text
+				removed = removed[1:]
+			} else {
+				d.Pos = pos
+			}
+			if len(added) > 0 {
+				d.Theirs = added[0].text
+				added = added[1:]
+			}
+			differences = append(differences, d)
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(ours) && j < len(theirs) {
+		if ours[i].text == theirs[j].text {
+			flush(ours[i].pos)
+			i, j = i+1, j+1
+		} else if lcs[i+1][j] >= lcs[i][j+1] {
+			removed = append(removed, ours[i])
+			i += 1
+		} else {
+			added = append(added, theirs[j])
+			j += 1
+		}
+	}
+	removed = append(removed, ours[i:]...)
+	added = append(added, theirs[j:]...)
+	var end lexer.Position
+	if len(ours) > 0 {
+		end = ours[len(ours)-1].pos
+	}
+	flush(end)
+	return
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template/parse"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func tParseStd(name, input string, mode parse.Mode) (tree *parse.Tree, err error) {
+	tree = parse.New(name)
+	tree.Mode = mode | parse.SkipFuncCheck
+	_, err = tree.Parse(input, "", "", map[string]*parse.Tree{})
+	return
+}
+
+func TestCompare(t *testing.T) {
+
+	Convey("conformance corpus", t, func() {
+		files, err := filepath.Glob("testdata/conformance/*")
+		So(err, ShouldBeNil)
+		So(files, ShouldNotBeEmpty)
+		for _, file := range files {
+			Convey(file, func() {
+				data, err := os.ReadFile(file)
+				So(err, ShouldBeNil)
+				input := string(data)
+
+				tree, err := ParseTemplate(file, input)
+				So(err, ShouldBeNil)
+				So(tree.Render(), ShouldEqual, input)
+
+				for _, mode := range []parse.Mode{0, parse.ParseComments} {
+					std, err := tParseStd(file, input, mode)
+					So(err, ShouldBeNil)
+					So(Compare(tree, std), ShouldBeEmpty)
+				}
+			})
+		}
+	})
+
+	Convey("disagreements", t, func() {
+		check := func(ours, theirs string) []Difference {
+			tree, err := ParseTemplate("ours.tmpl", ours)
+			So(err, ShouldBeNil)
+			std, err := tParseStd("theirs.tmpl", theirs, parse.ParseComments)
+			So(err, ShouldBeNil)
+			return Compare(tree, std)
+		}
+
+		Convey("identifiers", func() {
+			diffs := check(`a{{ printf "%v" .x }}`, `a{{ print "%v" .x }}`)
+			So(diffs, ShouldHaveLength, 1)
+			So(diffs[0].Ours, ShouldEqual, `action ident:printf string:"%v" field:.x`)
+			So(diffs[0].Theirs, ShouldEqual, `action ident:print string:"%v" field:.x`)
+			So(diffs[0].Pos.Offset, ShouldEqual, 1)
+			So(diffs[0].String(), ShouldStartWith, "ours.tmpl:1:2: ")
+		})
+
+		Convey("declarations", func() {
+			diffs := check(`{{ $x := 1 }}`, `{{ $x = 1 }}`)
+			So(diffs, ShouldHaveLength, 1)
+			So(diffs[0].Ours, ShouldEqual, `action $x := number:1`)
+			So(diffs[0].Theirs, ShouldEqual, `action $x = number:1`)
+		})
+
+		Convey("control keywords", func() {
+			diffs := check(`{{ if .x }}{{ end }}{{ .y }}`, `{{ with .x }}{{ end }}{{ .y }}`)
+			So(diffs, ShouldHaveLength, 1)
+			So(diffs[0].Ours, ShouldEqual, `if field:.x`)
+			So(diffs[0].Theirs, ShouldEqual, `with field:.x`)
+		})
+
+		Convey("missing and unexpected", func() {
+			diffs := check(`{{ .x }}{{ .y }}`, `{{ .x }}`)
+			So(diffs, ShouldHaveLength, 1)
+			So(diffs[0].Ours, ShouldEqual, `action field:.y`)
+			So(diffs[0].Theirs, ShouldEqual, ``)
+			So(diffs[0].String(), ShouldContainSubstring, "unexpected")
+			diffs = check(`{{ .x }}`, `{{ .x }}{{/* c */}}`)
+			So(diffs, ShouldHaveLength, 1)
+			So(diffs[0].Ours, ShouldEqual, ``)
+			So(diffs[0].Theirs, ShouldEqual, `comment /* c */`)
+			So(diffs[0].String(), ShouldContainSubstring, "missing")
+		})
+
+		Convey("structure errors", func() {
+			tree, err := ParseTemplate("ours.tmpl", `{{ if .x }}`)
+			So(err, ShouldBeNil)
+			diffs := Compare(tree, nil)
+			So(diffs, ShouldHaveLength, 1)
+			So(diffs[0].Ours, ShouldStartWith, "error: missing {{end}}")
+		})
+	})
+}
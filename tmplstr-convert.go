@@ -0,0 +1,147 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"fmt"
+	"strings"
+	"text/template/parse"
+)
+
+// ToParseTree converts the given Tree into a text/template/parse Tree. Inline
+// comments, which text/template does not support, are replaced with
+// whitespace so that all node positions within the parse.Tree are the same
+// byte offsets as the corresponding nodes within the given Tree. The action
+// delimiters are detected from the first Action present
+//
+// The returned parse.Tree is the main template only, {{define}} and {{block}}
+// bodies are parsed into separate trees by text/template/parse, see
+// ToParseTrees for all of them. Function names are not checked
+// (parse.SkipFuncCheck) and whole-action comments are retained
+// (parse.ParseComments)
+//
+// ToParseTree does not modify the given Tree and is safe to call from
+// multiple goroutines with the same Tree
+func ToParseTree(tree Tree) (stdTree *parse.Tree, err error) {
+	stdTree, _, err = toParseTrees(tree)
+	return
+}
+
+// ToParseTrees is like ToParseTree except that all the trees parsed by
+// text/template/parse are returned, keyed by name: the main template is named
+// after the filename of the given Tree and each {{define}} and {{block}} body
+// is named after the template it defines
+func ToParseTrees(tree Tree) (trees map[string]*parse.Tree, err error) {
+	_, trees, err = toParseTrees(tree)
+	return
+}
+
+func toParseTrees(tree Tree) (stdTree *parse.Tree, trees map[string]*parse.Tree, err error) {
+	var filename, source string
+	left, right := DefaultLeftDelim, DefaultRightDelim
+	var detected bool
+	for _, branch := range tree {
+		if filename == "" {
+			filename = branch.Pos.Filename
+		}
+		switch {
+		case branch.Invalid != nil:
+			return nil, nil, branch.Invalid.Err
+		case branch.Action != nil:
+			if !detected && branch.Action.Open != nil && branch.Action.Close != nil {
+				left = strings.TrimSuffix(*branch.Action.Open, "-")
+				right = strings.TrimPrefix(*branch.Action.Close, "-")
+				detected = true
+			}
+			source += branch.Action.renderWithoutInlineComments()
+		default:
+			source += branch.Render()
+		}
+	}
+	stdTree = parse.New(filename)
+	stdTree.Mode = parse.ParseComments | parse.SkipFuncCheck
+	trees = map[string]*parse.Tree{}
+	if _, err = stdTree.Parse(source, left, right, trees); err != nil {
+		return nil, nil, err
+	}
+	return
+}
+
+// FromParseTree parses the given source text, which the given parse.Tree was
+// parsed from, into a Tree and verifies that the two trees are structurally
+// the same using Compare. When there are any Differences, the Tree is still
+// returned along with a *ConformanceError
+//
+// The byte offsets of parse.Node positions can be used with Tree.BranchAt to
+// find the corresponding Branch, with all of its whitespace and comments
+func FromParseTree(stdTree *parse.Tree, source string) (tree Tree, err error) {
+	return gDefaultParser.FromParseTree(stdTree, source)
+}
+
+// FromParseTree is the Parser equivalent of the package-level FromParseTree
+// function, using this Parser's action delimiters
+func (p *Parser) FromParseTree(stdTree *parse.Tree, source string) (tree Tree, err error) {
+	if stdTree == nil {
+		return nil, fmt.Errorf("nil parse.Tree")
+	}
+	if tree, err = p.ParseTemplate(stdTree.ParseName, source); err != nil {
+		return nil, err
+	}
+	if differences := Compare(tree, stdTree); len(differences) > 0 {
+		err = &ConformanceError{Differences: differences}
+	}
+	return
+}
+
+// renderWithoutInlineComments returns the source text of this Action with
+// all inline comments replaced by spaces, preserving newlines and byte
+// lengths, without modifying this Action
+func (a *Action) renderWithoutInlineComments() (source string) {
+	source = *a.Open
+	for _, pipeline := range a.Pipelines {
+		source += pipeline.renderWithoutInlineComments()
+	}
+	source += *a.Close
+	return
+}
+
+// renderWithoutInlineComments is the Pipeline counterpart to
+// Action.renderWithoutInlineComments
+func (p *Pipeline) renderWithoutInlineComments() (source string) {
+	for _, v := range p.Root {
+		switch {
+		case v.Comment != nil && !v.ActionComment:
+			blank := strings.Map(func(r rune) rune {
+				if r == '\n' {
+					return r
+				}
+				return ' '
+			}, *v.Comment)
+			// multibyte runes were replaced with single spaces
+			source += blank + strings.Repeat(" ", len(*v.Comment)-len(blank))
+		case v.Grouping != nil && v.Grouping.Group != nil:
+			source += *v.Grouping.Open + v.Grouping.Group.renderWithoutInlineComments() + *v.Grouping.Close
+			if v.Grouping.Field != nil {
+				source += *v.Grouping.Field
+			}
+		default:
+			source += v.Render()
+		}
+	}
+	if p.Pipe != nil {
+		source += "|" + p.Pipe.renderWithoutInlineComments()
+	}
+	return
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"text/template/parse"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseTreeConversion(t *testing.T) {
+
+	Convey("ToParseTree", t, func() {
+
+		Convey("conformance corpus", func() {
+			files, err := filepath.Glob("testdata/conformance/*")
+			So(err, ShouldBeNil)
+			for _, file := range files {
+				data, err := os.ReadFile(file)
+				So(err, ShouldBeNil)
+				tree, err := ParseTemplate(file, string(data))
+				So(err, ShouldBeNil)
+				stdTree, err := ToParseTree(tree)
+				So(err, ShouldBeNil)
+				So(stdTree.ParseName, ShouldEqual, file)
+				So(Compare(tree, stdTree), ShouldBeEmpty)
+			}
+		})
+
+		Convey("inline comments keep offsets", func() {
+			input := "{{ print /* é\n */ .x }}"
+			tree, err := ParseTemplate("inline.tmpl", input)
+			So(err, ShouldBeNil)
+			stdTree, err := ToParseTree(tree)
+			So(err, ShouldBeNil)
+			action := stdTree.Root.Nodes[0].(*parse.ActionNode)
+			field := action.Pipe.Cmds[0].Args[1].(*parse.FieldNode)
+			So(int(field.Position()), ShouldEqual, len("{{ print /* é\n */ "))
+			So(tree.BranchAt(int(field.Position())), ShouldEqual, tree[0])
+			So(tree.Render(), ShouldEqual, input)
+		})
+
+		Convey("does not modify the tree", func() {
+			input := `{{ print (.x /* a */) /* b */ | upper }}`
+			tree, err := ParseTemplate("shared.tmpl", input)
+			So(err, ShouldBeNil)
+			var comments []*string
+			tree.WalkVariables(func(variables *Variables) (stop bool) {
+				for _, v := range *variables {
+					if v.Comment != nil {
+						comments = append(comments, v.Comment)
+					}
+				}
+				return
+			})
+			So(comments, ShouldHaveLength, 2)
+
+			var wg sync.WaitGroup
+			results := make([]string, 8)
+			for idx := range results {
+				wg.Add(1)
+				go func(idx int) {
+					defer wg.Done()
+					if stdTree, err := ToParseTree(tree); err == nil {
+						results[idx] = stdTree.Root.String()
+					}
+				}(idx)
+			}
+			wg.Wait()
+			for _, result := range results {
+				So(result, ShouldEqual, `{{print (.x) | upper}}`)
+			}
+			So(tree.Render(), ShouldEqual, input)
+			var after []*string
+			tree.WalkVariables(func(variables *Variables) (stop bool) {
+				for _, v := range *variables {
+					if v.Comment != nil {
+						after = append(after, v.Comment)
+					}
+				}
+				return
+			})
+			So(after, ShouldEqual, comments)
+		})
+
+		Convey("ToParseTrees", func() {
+			tree, err := ParseTemplate("defines.tmpl", `main {{ template "a" }}{{ define "a" }}A{{ end }}{{ block "b" . }}B{{ end }}`)
+			So(err, ShouldBeNil)
+			trees, err := ToParseTrees(tree)
+			So(err, ShouldBeNil)
+			So(trees, ShouldHaveLength, 3)
+			So(trees["defines.tmpl"].Root.String(), ShouldEqual, `main {{template "a"}}{{template "b" .}}`)
+			So(trees["a"].Root.String(), ShouldEqual, "A")
+			So(trees["b"].Root.String(), ShouldEqual, "B")
+
+			stdTree, err := ToParseTree(tree)
+			So(err, ShouldBeNil)
+			So(stdTree.Name, ShouldEqual, "defines.tmpl")
+
+			tree, err = ParseTemplate("only.tmpl", "{{ define \"a\" }}A{{ end }}\n")
+			So(err, ShouldBeNil)
+			trees, err = ToParseTrees(tree)
+			So(err, ShouldBeNil)
+			So(trees, ShouldHaveLength, 2)
+			So(trees["a"].Root.String(), ShouldEqual, "A")
+			So(parse.IsEmptyTree(trees["only.tmpl"].Root), ShouldBeTrue)
+
+			tree, err = ParseTemplateTolerant("invalid.tmpl", "{{ ( }}")
+			So(err, ShouldNotBeNil)
+			_, err = ToParseTrees(tree)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("custom delimiters", func() {
+			p := MustNewParser(WithDelims("[[", "]]"))
+			tree, err := p.ParseTemplate("delims.tmpl", "{{ text }} [[- if .x ]]y[[ end ]]")
+			So(err, ShouldBeNil)
+			stdTree, err := ToParseTree(tree)
+			So(err, ShouldBeNil)
+			So(stdTree.Root.String(), ShouldEqual, "{{ text }}[[if .x]]y[[end]]")
+		})
+
+		Convey("invalid actions", func() {
+			tree, err := ParseTemplateTolerant("invalid.tmpl", "{{ .x }}{{ ( }}")
+			So(err, ShouldNotBeNil)
+			_, err = ToParseTree(tree)
+			So(err, ShouldNotBeNil)
+			var pe *ParseError
+			So(errors.As(err, &pe), ShouldBeTrue)
+		})
+
+		Convey("text/template errors", func() {
+			tree, err := ParseTemplate("unclosed.tmpl", "{{ if .x }}")
+			So(err, ShouldBeNil)
+			_, err = ToParseTree(tree)
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("FromParseTree", t, func() {
+		input := `{{/* note */}}{{ with $v := .x }}{{ $v /* inline */ }}{{ end }}`
+		stdTree, err := tParseStd("from.tmpl", `{{/* note */}}{{ with $v := .x }}{{ $v }}{{ end }}`, parse.ParseComments)
+		So(err, ShouldBeNil)
+
+		tree, err := FromParseTree(stdTree, input)
+		So(err, ShouldBeNil)
+		So(tree.Render(), ShouldEqual, input)
+		So(tree[0].Pos.Filename, ShouldEqual, "from.tmpl")
+
+		_, err = FromParseTree(nil, input)
+		So(err, ShouldNotBeNil)
+
+		_, err = FromParseTree(stdTree, "{{ (")
+		So(err, ShouldNotBeNil)
+
+		tree, err = FromParseTree(stdTree, `{{/* note */}}{{ with $v := .y }}{{ $v }}{{ end }}`)
+		So(tree, ShouldNotBeNil)
+		var ce *ConformanceError
+		So(errors.As(err, &ce), ShouldBeTrue)
+		So(ce.Differences, ShouldNotBeEmpty)
+		So(ce.Error(), ShouldStartWith, "trees do not conform: ")
+	})
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+// Severity is the importance of a Diagnostic
+type Severity int
+
+const (
+	// SeverityError is for problems that prevent the template from working
+	SeverityError Severity = iota
+	// SeverityWarning is for likely mistakes that do not stop the template
+	SeverityWarning
+	// SeverityInfo is for suggestions that do not change the template output
+	SeverityInfo
+)
+
+// String returns the lowercase name of this Severity
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	}
+	return fmt.Sprintf("severity(%d)", int(s))
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (s Severity) MarshalText() (text []byte, err error) {
+	return []byte(s.String()), nil
+}
+
+// Diagnostic is a single problem found while analysing templates
+type Diagnostic struct {
+	// Pos is the position of the problem, the zero value when the problem is
+	// not related to any particular position
+	Pos lexer.Position `json:"pos"`
+	// Severity is the importance of the problem
+	Severity Severity `json:"severity"`
+	// Code is a short, stable identifier for the kind of problem, such as
+	// "unknown-func"
+	Code string `json:"code"`
+	// Message describes the problem
+	Message string `json:"message"`
+}
+
+// String returns the Diagnostic in "file:line:column: severity: message
+// (code)" form, without the position when there is none
+func (d Diagnostic) String() (text string) {
+	if d.Pos.Line > 0 {
+		text = d.Pos.String() + ": "
+	}
+	text += d.Severity.String() + ": " + d.Message
+	if d.Code != "" {
+		text += " (" + d.Code + ")"
+	}
+	return
+}
+
+// Diagnostics is a list of Diagnostic instances
+type Diagnostics []Diagnostic
+
+// Less returns true if this Diagnostic sorts before the other given, by
+// filename, offset and code
+func (d Diagnostic) Less(other Diagnostic) bool {
+	if d.Pos.Filename != other.Pos.Filename {
+		return d.Pos.Filename < other.Pos.Filename
+	} else if d.Pos.Offset != other.Pos.Offset {
+		return d.Pos.Offset < other.Pos.Offset
+	}
+	return d.Code < other.Code
+}
+
+// Sort orders these Diagnostics by filename, offset and code
+func (ds Diagnostics) Sort() {
+	sort.SliceStable(ds, func(i, j int) bool {
+		return ds[i].Less(ds[j])
+	})
+}
+
+// HasErrors returns true if any of these Diagnostics are SeverityError
+func (ds Diagnostics) HasErrors() bool {
+	for _, d := range ds {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
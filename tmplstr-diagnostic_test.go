@@ -0,0 +1,54 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/alecthomas/participle/v2/lexer"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDiagnostic(t *testing.T) {
+	Convey("Severity", t, func() {
+		So(SeverityError.String(), ShouldEqual, "error")
+		So(SeverityWarning.String(), ShouldEqual, "warning")
+		So(SeverityInfo.String(), ShouldEqual, "info")
+		So(Severity(9).String(), ShouldEqual, "severity(9)")
+	})
+
+	Convey("Diagnostics", t, func() {
+		ds := Diagnostics{
+			{Pos: lexer.Position{Filename: "b.tmpl", Offset: 1, Line: 1, Column: 2}, Severity: SeverityWarning, Code: "b", Message: "second file"},
+			{Pos: lexer.Position{Filename: "a.tmpl", Offset: 5, Line: 1, Column: 6}, Severity: SeverityInfo, Message: "later"},
+			{Pos: lexer.Position{Filename: "a.tmpl", Offset: 0, Line: 1, Column: 1}, Severity: SeverityInfo, Code: "a", Message: "first"},
+		}
+		So(ds.HasErrors(), ShouldBeFalse)
+		ds.Sort()
+		So(ds[0].String(), ShouldEqual, "a.tmpl:1:1: info: first (a)")
+		So(ds[1].String(), ShouldEqual, "a.tmpl:1:6: info: later")
+		So(ds[2].String(), ShouldEqual, "b.tmpl:1:2: warning: second file (b)")
+		So(Diagnostic{Severity: SeverityError, Message: "global"}.String(), ShouldEqual, "error: global")
+		So(ds[0].Less(ds[1]), ShouldBeTrue)
+		So(ds[1].Less(ds[0]), ShouldBeFalse)
+		So(ds[0].Less(ds[0]), ShouldBeFalse)
+		So(Diagnostic{Code: "a"}.Less(Diagnostic{Code: "b"}), ShouldBeTrue)
+
+		data, err := json.Marshal(ds[0])
+		So(err, ShouldBeNil)
+		So(string(data), ShouldContainSubstring, `"severity":"info"`)
+	})
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+// TextEdit replaces the source text from Pos up to, but not including, End
+// with the Text given. The Pos and End are typically taken from the Pos and
+// EndPos of Tree nodes and only their Offset values are used
+//
+// A TextEdit with Pos equal to End inserts Text and one with an empty Text
+// deletes the range
+type TextEdit struct {
+	Pos  lexer.Position `json:"pos"`
+	End  lexer.Position `json:"end"`
+	Text string         `json:"text"`
+}
+
+// NewTextEdit returns a TextEdit replacing the source text from pos to end
+func NewTextEdit(pos, end lexer.Position, text string) (edit TextEdit) {
+	return TextEdit{Pos: pos, End: end, Text: text}
+}
+
+// Overlaps returns true if this TextEdit and the other given cannot both be
+// applied. Ranges which only touch do not overlap, except for insertions at
+// the same offset which have no defined order
+func (e TextEdit) Overlaps(other TextEdit) bool {
+	if e.Pos.Offset == other.Pos.Offset && (e.Pos.Offset == e.End.Offset || other.Pos.Offset == other.End.Offset) {
+		return true
+	}
+	return e.Pos.Offset < other.End.Offset && other.Pos.Offset < e.End.Offset
+}
+
+// EditConflictError is returned by ApplyEdits when two edits overlap
+type EditConflictError struct {
+	A, B TextEdit
+}
+
+func (e *EditConflictError) Error() string {
+	return fmt.Sprintf("conflicting edits at offsets %d-%d and %d-%d", e.A.Pos.Offset, e.A.End.Offset, e.B.Pos.Offset, e.B.End.Offset)
+}
+
+// ApplyEdits returns the given source text with all the edits applied. The
+// edits may be given in any order and identical edits are only applied once.
+// When any edit is out of range or any two edits overlap, the source text is
+// returned unchanged along with an error, an *EditConflictError for
+// overlapping edits
+func ApplyEdits(source string, edits []TextEdit) (edited string, err error) {
+	sorted := make([]TextEdit, 0, len(edits))
+	for _, edit := range edits {
+		if edit.Pos.Offset < 0 || edit.End.Offset < edit.Pos.Offset || edit.End.Offset > len(source) {
+			return source, fmt.Errorf("edit out of range: %d-%d of %d bytes", edit.Pos.Offset, edit.End.Offset, len(source))
+		}
+		sorted = append(sorted, edit)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Pos.Offset < sorted[j].Pos.Offset
+	})
+
+	var sb strings.Builder
+	var last *TextEdit
+	var offset int
+	for idx := range sorted {
+		edit := &sorted[idx]
+		if last != nil {
+			if edit.Pos.Offset == last.Pos.Offset && edit.End.Offset == last.End.Offset && edit.Text == last.Text {
+				continue // duplicate
+			} else if edit.Overlaps(*last) {
+				return source, &EditConflictError{A: *last, B: *edit}
+			}
+		}
+		sb.WriteString(source[offset:edit.Pos.Offset])
+		sb.WriteString(edit.Text)
+		offset = edit.End.Offset
+		last = edit
+	}
+	sb.WriteString(source[offset:])
+	return sb.String(), nil
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alecthomas/participle/v2/lexer"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func tEdit(pos, end int, text string) TextEdit {
+	return NewTextEdit(lexer.Position{Offset: pos}, lexer.Position{Offset: end}, text)
+}
+
+func TestApplyEdits(t *testing.T) {
+	Convey("Overlaps", t, func() {
+		So(tEdit(0, 2, "").Overlaps(tEdit(2, 4, "")), ShouldBeFalse)
+		So(tEdit(0, 3, "").Overlaps(tEdit(2, 4, "")), ShouldBeTrue)
+		So(tEdit(2, 4, "").Overlaps(tEdit(0, 3, "")), ShouldBeTrue)
+		So(tEdit(0, 4, "").Overlaps(tEdit(1, 2, "")), ShouldBeTrue)
+		So(tEdit(1, 1, "a").Overlaps(tEdit(1, 1, "b")), ShouldBeTrue)
+		So(tEdit(1, 1, "a").Overlaps(tEdit(1, 3, "")), ShouldBeTrue)
+		So(tEdit(1, 1, "a").Overlaps(tEdit(0, 1, "")), ShouldBeFalse)
+		So(tEdit(0, 2, "").Overlaps(tEdit(1, 1, "a")), ShouldBeTrue)
+	})
+
+	Convey("applying", t, func() {
+		edited, err := ApplyEdits("hello world", nil)
+		So(err, ShouldBeNil)
+		So(edited, ShouldEqual, "hello world")
+
+		edited, err = ApplyEdits("hello world", []TextEdit{
+			tEdit(6, 11, "there"),
+			tEdit(0, 0, "> "),
+			tEdit(5, 6, ", "),
+		})
+		So(err, ShouldBeNil)
+		So(edited, ShouldEqual, "> hello, there")
+
+		edited, err = ApplyEdits("hello world", []TextEdit{tEdit(0, 6, ""), tEdit(0, 6, "")})
+		So(err, ShouldBeNil)
+		So(edited, ShouldEqual, "world")
+	})
+
+	Convey("errors", t, func() {
+		edited, err := ApplyEdits("hello", []TextEdit{tEdit(0, 3, "a"), tEdit(2, 4, "b")})
+		So(edited, ShouldEqual, "hello")
+		var conflict *EditConflictError
+		So(errors.As(err, &conflict), ShouldBeTrue)
+		So(conflict.A.Text, ShouldEqual, "a")
+		So(conflict.B.Text, ShouldEqual, "b")
+		So(err.Error(), ShouldEqual, "conflicting edits at offsets 0-3 and 2-4")
+
+		_, err = ApplyEdits("hello", []TextEdit{tEdit(1, 1, "a"), tEdit(1, 1, "b")})
+		So(err, ShouldNotBeNil)
+
+		edited, err = ApplyEdits("hello", []TextEdit{tEdit(4, 6, "")})
+		So(edited, ShouldEqual, "hello")
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "edit out of range: 4-6 of 5 bytes")
+		_, err = ApplyEdits("hello", []TextEdit{tEdit(3, 2, "")})
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("tree positions", t, func() {
+		source := `{{ print (.x) }} and {{ upper "a" }}`
+		tree, err := ParseTemplate("edit.tmpl", source)
+		So(err, ShouldBeNil)
+		grouping := tree[0].Action.Pipelines[0].Root[3]
+		call := tree[2].Action.Pipelines[0].Root[1]
+		edited, err := ApplyEdits(source, []TextEdit{
+			NewTextEdit(call.Pos, call.EndPos, "lower"),
+			NewTextEdit(grouping.Pos, grouping.EndPos, ".x"),
+		})
+		So(err, ShouldBeNil)
+		So(edited, ShouldEqual, `{{ print .x }} and {{ lower "a" }}`)
+	})
+}
@@ -0,0 +1,168 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/participle/v2"
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+var (
+	rxExpectedClause = regexp.MustCompile(`\(expected (.+)\)\s*$`)
+	rxExpectedSymbol = regexp.MustCompile(`<([a-zA-Z]+)>`)
+)
+
+// ParseError is returned by ParseTemplate when an action fails to parse. All
+// positional information is relative to the complete input given to
+// ParseTemplate and not just the offending action
+type ParseError struct {
+	// Pos is the filename, byte offset, line and column of the error
+	Pos lexer.Position `json:"pos"`
+	// Action is the complete source text of the offending action
+	Action string `json:"action"`
+	// Expected is the list of token kinds the parser was expecting, if known
+	Expected []string `json:"expected,omitempty"`
+	// Message is the unadorned error message
+	Message string `json:"message"`
+	// Err is the underlying parser error
+	Err error `json:"-"`
+
+	line string
+}
+
+// newParseError constructs a new ParseError from the given err which happened
+// while parsing the action source text found at the given base position
+// within the input
+func (p *Parser) newParseError(input, action string, base lexer.Position, err error) (pe *ParseError) {
+	pe = &ParseError{
+		Pos:     base,
+		Action:  action,
+		Message: err.Error(),
+		Err:     err,
+	}
+
+	var perr participle.Error
+	if errors.As(err, &perr) {
+		pe.Message = perr.Message()
+		if pos := perr.Position(); pos.Line > 0 {
+			pe.Pos = base.Add(pos)
+		}
+	}
+	pe.Expected = parseExpectedSymbols(p.lexer.Symbols(), pe.Message)
+
+	start, end := pe.Pos.Offset, pe.Pos.Offset
+	for start > 0 && input[start-1] != '\n' {
+		start -= 1
+	}
+	for end < len(input) && input[end] != '\n' {
+		end += 1
+	}
+	pe.line = input[start:end]
+	return
+}
+
+// parseExpectedSymbols returns the proper lexer symbol names for any
+// `<symbol>` references found in the participle error message
+func parseExpectedSymbols(symbols map[string]lexer.TokenType, message string) (expected []string) {
+	clause := message
+	if m := rxExpectedClause.FindStringSubmatch(message); len(m) == 2 {
+		clause = m[1]
+	}
+	unique := make(map[string]struct{})
+	for _, m := range rxExpectedSymbol.FindAllStringSubmatch(clause, -1) {
+		for name := range symbols {
+			if strings.EqualFold(name, m[1]) {
+				if _, present := unique[name]; !present {
+					unique[name] = struct{}{}
+					expected = append(expected, name)
+				}
+				break
+			}
+		}
+	}
+	return
+}
+
+// Error returns the error message prefixed with the filename, line and column
+func (e *ParseError) Error() string {
+	return e.Pos.String() + ": " + e.Message
+}
+
+// Unwrap returns the underlying parser error
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Excerpt returns the complete source line containing the error followed by
+// a second line with a caret pointing at the error column
+func (e *ParseError) Excerpt() (excerpt string) {
+	var caret string
+	for idx, r := range []rune(e.line) {
+		if idx >= e.Pos.Column-1 {
+			break
+		} else if r == '\t' {
+			caret += "\t"
+		} else {
+			caret += " "
+		}
+	}
+	excerpt = e.line + "\n" + caret + "^"
+	return
+}
+
+// ParseErrors is a list of ParseError instances, returned by
+// ParseTemplateTolerant when any actions failed to parse
+type ParseErrors []*ParseError
+
+// Error returns the error messages of all the ParseErrors, one per line
+func (e ParseErrors) Error() (message string) {
+	for idx, pe := range e {
+		if idx > 0 {
+			message += "\n"
+		}
+		message += pe.Error()
+	}
+	return
+}
+
+// Unwrap returns the list of ParseErrors as a list of errors
+func (e ParseErrors) Unwrap() (errs []error) {
+	for _, pe := range e {
+		errs = append(errs, pe)
+	}
+	return
+}
+
+// ConformanceError is returned by FromParseTree when the Tree parsed from the
+// source text does not match the text/template/parse Tree given
+type ConformanceError struct {
+	Differences []Difference
+}
+
+// Error returns the first Difference found and the total number of them
+func (e *ConformanceError) Error() (message string) {
+	switch len(e.Differences) {
+	case 0:
+		return "trees do not conform"
+	case 1:
+		return "trees do not conform: " + e.Differences[0].String()
+	}
+	return fmt.Sprintf("trees do not conform: %s (and %d more)", e.Differences[0].String(), len(e.Differences)-1)
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alecthomas/participle/v2"
+	"github.com/alecthomas/participle/v2/lexer"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseError(t *testing.T) {
+	Convey("ParseTemplate errors", t, func() {
+
+		Convey("unexpected token", func() {
+			input := "first line\n\tsecond {{ if [ }} line"
+			tree, err := ParseTemplate("errors.tmpl", input)
+			So(tree, ShouldBeNil)
+			So(err, ShouldNotBeNil)
+			var pe *ParseError
+			So(errors.As(err, &pe), ShouldBeTrue)
+			So(pe.Pos, ShouldEqual, lexer.Position{Filename: "errors.tmpl", Offset: 25, Line: 2, Column: 15})
+			So(input[pe.Pos.Offset:pe.Pos.Offset+1], ShouldEqual, "[")
+			So(pe.Action, ShouldEqual, "{{ if [ }}")
+			So(pe.Expected, ShouldEqual, []string{"StatementClose"})
+			So(pe.Message, ShouldStartWith, "unexpected token")
+			So(pe.Error(), ShouldStartWith, "errors.tmpl:2:15: unexpected token")
+			So(pe.Excerpt(), ShouldEqual, "\tsecond {{ if [ }} line\n\t             ^")
+			var ute *participle.UnexpectedTokenError
+			So(errors.As(err, &ute), ShouldBeTrue)
+		})
+
+		Convey("empty action", func() {
+			_, err := ParseTemplate("errors.tmpl", "one {{}}")
+			var pe *ParseError
+			So(errors.As(err, &pe), ShouldBeTrue)
+			So(pe.Pos.Offset, ShouldEqual, 6)
+			So(pe.Pos.Column, ShouldEqual, 7)
+			So(pe.Expected, ShouldEqual, []string{"Space"})
+			So(pe.Excerpt(), ShouldEqual, "one {{}}\n      ^")
+		})
+
+		Convey("unterminated comments and quotations", func() {
+			for input, message := range map[string]string{
+				"a {{ /* x }} b":  "unclosed comment",
+				`a {{ "x }} b`:    "unterminated quoted string",
+				"a {{ 'x }} b":    "unterminated character constant",
+				"a {{ `x }} b":    "unterminated raw quoted string",
+				"a {{ /* x\n}} b": "unclosed comment",
+				"a {{ \"x }}\nb":  "unterminated quoted string",
+				"a {{ 'x }}\nb":   "unterminated character constant",
+			} {
+				tree, err := ParseTemplate("errors.tmpl", input)
+				So(tree, ShouldBeNil)
+				var pe *ParseError
+				So(errors.As(err, &pe), ShouldBeTrue)
+				So(pe.Message, ShouldEqual, message)
+				So(pe.Pos, ShouldEqual, lexer.Position{Filename: "errors.tmpl", Offset: 2, Line: 1, Column: 3})
+				So(pe.Action, ShouldEqual, input[2:])
+			}
+
+			tree, err := ParseTemplateTolerant("errors.tmpl", "a {{ . }} b {{ /* x }} c")
+			So(err, ShouldNotBeNil)
+			So(tree, ShouldHaveLength, 4)
+			So(tree[3].Invalid, ShouldNotBeNil)
+			So(tree[3].Invalid.Source, ShouldEqual, "{{ /* x }} c")
+			So(tree.Render(), ShouldEqual, "a {{ . }} b {{ /* x }} c")
+
+			// quoted strings end at a newline, even after the right delimiter
+			tree, err = ParseTemplateTolerant("errors.tmpl", "x {{ \"unterminated }}\nmore")
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "unterminated quoted string")
+			So(tree, ShouldHaveLength, 2)
+			So(tree[1].Invalid, ShouldNotBeNil)
+			So(RemoveTemplateComments("x {{ \"unterminated }}\nmore"), ShouldEqual, "x {{ \"unterminated }}\nmore")
+
+			// text outside of actions is not scanned
+			tree, err = ParseTemplate("errors.tmpl", `a "b /* c`)
+			So(err, ShouldBeNil)
+			So(tree, ShouldHaveLength, 1)
+		})
+
+		Convey("unclosed actions", func() {
+			for input, offset := range map[string]int{
+				"a {{ .x ":           2,
+				"a {{ .x }} b {{ .y": 13,
+			} {
+				tree, err := ParseTemplate("errors.tmpl", input)
+				So(tree, ShouldBeNil)
+				var pe *ParseError
+				So(errors.As(err, &pe), ShouldBeTrue)
+				So(pe.Message, ShouldEqual, "unclosed action")
+				So(pe.Pos.Offset, ShouldEqual, offset)
+				So(pe.Action, ShouldEqual, input[offset:])
+
+				tree, err = ParseTemplateTolerant("errors.tmpl", input)
+				So(err, ShouldNotBeNil)
+				last := tree[len(tree)-1]
+				So(last.Invalid, ShouldNotBeNil)
+				So(last.Invalid.Source, ShouldEqual, input[offset:])
+				So(last.Invalid.Err.Error(), ShouldContainSubstring, "unclosed action")
+				So(tree.Render(), ShouldEqual, input)
+				So(RemoveTemplateComments(input), ShouldEqual, input)
+			}
+		})
+
+		Convey("without participle position", func() {
+			pe := gDefaultParser.newParseError("{{ x }}", "{{ x }}", lexer.Position{Filename: "plain.tmpl", Line: 1, Column: 1}, errors.New("plain"))
+			So(pe.Error(), ShouldEqual, "plain.tmpl:1:1: plain")
+			So(pe.Expected, ShouldBeNil)
+			So(pe.Unwrap().Error(), ShouldEqual, "plain")
+		})
+	})
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	rxFormatDeclare = regexp.MustCompile(`^(range\s+)?(.+?)\s*(:?=)$`)
+	rxFormatComma   = regexp.MustCompile(`\s*,\s*`)
+)
+
+// FormatOptions configures the output of Format
+type FormatOptions struct {
+	// NestPadding is repeated once for each level of nesting within control
+	// structures and inserted inside each action, between the opening
+	// delimiter (and padding) and the pipeline, so `{{ if .x }}{{ .y }}`
+	// becomes `{{ if .x }}{{   .y }}` with two spaces. Text outside the
+	// actions is never changed, since that would change the template output.
+	// Comment-only actions are not padded because text/template requires the
+	// comment to immediately follow the delimiter. An empty NestPadding
+	// disables the padding
+	NestPadding string
+	// Compact removes the padding spaces between the action delimiters and
+	// the pipeline, except where a trim marker requires one
+	Compact bool
+}
+
+// Format returns the canonical source text of the given Tree. Text and
+// Invalid branches are left untouched while within each action:
+//
+//   - trim markers are always separated from the pipeline by one space
+//   - the pipeline is padded with one space on each side (see Compact)
+//   - operands are separated by one space
+//   - `|` has one space on each side
+//   - `:=` and `=` have one space on each side
+//   - range declarations are written as `range $i, $e :=`
+//   - Groupings have no padding within the parentheses
+//   - comment-only actions are written as `{{/* ... */}}` or with trim
+//     markers as `{{- /* ... */ -}}`
+//
+// Literals keep their original spelling and comments are never changed. The
+// result parses to the same text/template structure as the given Tree and
+// formatting the result again returns the same text
+func Format(tree Tree, opts FormatOptions) (source string) {
+	var depth int
+	for _, branch := range tree {
+		if branch.Action == nil || branch.Invalid != nil {
+			source += branch.Render()
+			continue
+		}
+		level := depth
+		switch branch.Action.Control() {
+		case "if", "range", "with", "define", "block":
+			depth += 1
+		case "else", "else if", "else with":
+			level -= 1
+		case "end":
+			depth -= 1
+			level = depth
+		}
+		source += formatAction(branch.Action, max(level, 0), opts)
+	}
+	return
+}
+
+// formatAction returns the canonical source text of the given Action at the
+// given nesting level
+func formatAction(action *Action, level int, opts FormatOptions) (source string) {
+	opener, closer := *action.Open, *action.Close
+	leftTrim, rightTrim := strings.HasSuffix(opener, "-"), strings.HasPrefix(closer, "-")
+	left, right := strings.TrimSuffix(opener, "-"), strings.TrimPrefix(closer, "-")
+
+	if comment := action.actionComment(); comment != nil {
+		source = left
+		if leftTrim {
+			source += "- "
+		}
+		source += *comment.Comment
+		if rightTrim {
+			source += " -"
+		}
+		return source + right
+	}
+
+	var pipelines []string
+	for _, pipeline := range action.Pipelines {
+		pipelines = append(pipelines, formatPipeline(pipeline))
+	}
+	body := strings.Join(pipelines, " ")
+
+	source = left
+	if leftTrim {
+		source += "-"
+	}
+	if leftTrim || !opts.Compact {
+		source += " "
+	}
+	source += strings.Repeat(opts.NestPadding, level) + body
+	if rightTrim || !opts.Compact {
+		source += " "
+	}
+	if rightTrim {
+		source += "-"
+	}
+	return source + right
+}
+
+// formatPipeline returns the canonical source text of the given Pipeline
+func formatPipeline(pipeline *Pipeline) (source string) {
+	for pipe := pipeline; pipe != nil; pipe = pipe.Pipe {
+		if pipe != pipeline {
+			source += " | "
+		}
+		var operands []string
+		for _, v := range pipe.Root {
+			switch {
+			case v.Space != nil:
+			case v.Grouping != nil:
+				operands = append(operands, formatGrouping(v.Grouping))
+			case v.Assign != nil:
+				operands = append(operands, formatDeclare(*v.Assign))
+			case v.Range != nil:
+				operands = append(operands, formatDeclare(*v.Range))
+			default:
+				operands = append(operands, v.Render())
+			}
+		}
+		source += strings.Join(operands, " ")
+	}
+	return
+}
+
+// formatGrouping returns the canonical source text of the given Grouping
+func formatGrouping(g *Grouping) (source string) {
+	source = "("
+	if g.Group != nil {
+		source += formatPipeline(g.Group)
+	}
+	source += ")"
+	if g.Field != nil {
+		source += *g.Field
+	}
+	return
+}
+
+// formatDeclare returns the canonical form of Assign and Range tokens, like
+// `$x :=` and `range $i, $e :=`
+func formatDeclare(token string) (source string) {
+	m := rxFormatDeclare.FindStringSubmatch(token)
+	if m == nil {
+		return token
+	}
+	if m[1] != "" {
+		source = "range "
+	}
+	source += rxFormatComma.ReplaceAllString(m[2], ", ") + " " + m[3]
+	return
+}
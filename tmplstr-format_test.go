@@ -0,0 +1,105 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template/parse"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// tFormatStable asserts that formatting the given input is idempotent and
+// does not change the text/template structure, returning the formatted text
+func tFormatStable(name, input string, opts FormatOptions) (formatted string) {
+	tree, err := ParseTemplate(name, input)
+	So(err, ShouldBeNil)
+	formatted = Format(tree, opts)
+
+	again, err := ParseTemplate(name, formatted)
+	So(err, ShouldBeNil)
+	So(Format(again, opts), ShouldEqual, formatted)
+
+	if before, err := tParseStd(name, input, parse.ParseComments); err == nil {
+		after, err := tParseStd(name, formatted, parse.ParseComments)
+		So(err, ShouldBeNil)
+		So(after.Root.String(), ShouldEqual, before.Root.String())
+	}
+	return
+}
+
+func TestFormat(t *testing.T) {
+
+	Convey("spacing", t, func() {
+		for _, test := range []struct {
+			input, output string
+		}{
+			{`{{.x}}`, `{{ .x }}`},
+			{"{{   .x   .y\n\t}}", `{{ .x .y }}`},
+			{`{{.x|upper|printf "%s"}}`, `{{ .x | upper | printf "%s" }}`},
+			{`{{$x:=.y}}{{$x=1}}`, `{{ $x := .y }}{{ $x = 1 }}`},
+			{`{{range $i ,$e:= .l}}{{end}}`, `{{ range $i, $e := .l }}{{ end }}`},
+			{`{{ print ( len  .x ) (.y).Z }}`, `{{ print (len .x) (.y).Z }}`},
+			{`{{-.x-}}`, `{{- .x -}}`},
+			{`{{-   .x   -}}`, `{{- .x -}}`},
+			{`{{-3}}`, `{{ -3 }}`},
+			{`{{/* c */}}{{- /*  keep   me */ -}}`, `{{/* c */}}{{- /*  keep   me */ -}}`},
+			{`{{ _ "k"   /* note */   .Arg }}`, `{{ _ "k" /* note */ .Arg }}`},
+			{`{{ "\x41"  1.50  'é' }}`, `{{ "\x41" 1.50 'é' }}`},
+			{"  text\n\t{{.x}}  more  ", "  text\n\t{{ .x }}  more  "},
+		} {
+			So(Format(tMustParse(test.input), FormatOptions{}), ShouldEqual, test.output)
+		}
+	})
+
+	Convey("compact", t, func() {
+		So(Format(tMustParse(`{{ .x }}{{- .y -}}{{ /* c */ }}`), FormatOptions{Compact: true}), ShouldEqual, `{{.x}}{{- .y -}}{{/* c */}}`)
+	})
+
+	Convey("nest padding", t, func() {
+		input := "{{if .a}}\n{{range .b}}\n{{.c}}\n{{else}}\n{{.d}}\n{{end}}\n{{else if .e}}\n{{end}}\n{{define \"x\"}}{{.f}}{{end}}"
+		So(tFormatStable("indent.tmpl", input, FormatOptions{NestPadding: "  "}), ShouldEqual,
+			"{{ if .a }}\n{{   range .b }}\n{{     .c }}\n{{   else }}\n{{     .d }}\n{{   end }}\n{{ else if .e }}\n{{ end }}\n{{ define \"x\" }}{{   .f }}{{ end }}")
+
+		// unbalanced structures do not pad below zero
+		So(Format(tMustParse(`{{ end }}{{ .x }}`), FormatOptions{NestPadding: "\t"}), ShouldEqual, `{{ end }}{{ .x }}`)
+	})
+
+	Convey("invalid branches", t, func() {
+		tree, err := ParseTemplateTolerant("invalid.tmpl", `{{.x}}{{ ( }}`)
+		So(err, ShouldNotBeNil)
+		So(Format(tree, FormatOptions{}), ShouldEqual, `{{ .x }}{{ ( }}`)
+	})
+
+	Convey("idempotency", t, func() {
+		files, err := filepath.Glob("testdata/conformance/*")
+		So(err, ShouldBeNil)
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			So(err, ShouldBeNil)
+			for _, opts := range []FormatOptions{{}, {NestPadding: "  "}, {Compact: true}} {
+				tFormatStable(file, string(data), opts)
+			}
+		}
+	})
+}
+
+func tMustParse(input string) (tree Tree) {
+	tree, err := ParseTemplate("format.tmpl", input)
+	So(err, ShouldBeNil)
+	return
+}
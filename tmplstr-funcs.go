@@ -0,0 +1,171 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+// BuiltinFuncs is the list of functions predefined by text/template
+//
+// See: https://pkg.go.dev/text/template#hdr-Functions
+var BuiltinFuncs = []string{
+	"and", "call", "html", "index", "slice", "js", "len", "not", "or",
+	"print", "printf", "println", "urlquery",
+	"eq", "ge", "gt", "le", "lt", "ne",
+}
+
+// FuncCall is a single function call found by FuncCalls
+//
+// Example: `{{ .Name | printf "%s: %d" .Count }}` has one FuncCall with the
+// Name "printf", the Args `"%s: %d"` and `.Count`, and Piped true
+type FuncCall struct {
+	// Name is the function name
+	Name string `json:"name"`
+	// Ident is the Ident Variable in function position
+	Ident *Variable `json:"-"`
+	// Args are the significant argument Variables, not including any piped
+	// value
+	Args Variables `json:"-"`
+	// Piped is true when the result of the previous command is passed as
+	// the final argument
+	Piped bool `json:"piped,omitempty"`
+	// Pos is the position of the Ident
+	Pos lexer.Position `json:"pos"`
+}
+
+// NumArgs returns the number of arguments the function is called with,
+// including any piped value
+func (c FuncCall) NumArgs() (count int) {
+	count = len(c.Args)
+	if c.Piped {
+		count += 1
+	}
+	return
+}
+
+// FuncCalls returns all function calls within the given Tree, in source
+// order. A function call is an Ident Variable which is the first Variable
+// of a pipeline command (after any control keyword and declarations), or the
+// first Variable after a `|`, including those within Groupings
+func FuncCalls(tree Tree) (calls []FuncCall) {
+	for _, branch := range tree {
+		if branch.Action == nil || len(branch.Action.Pipelines) == 0 {
+			continue
+		}
+		action := branch.Action
+		walkCommands(action.Pipelines[0], action.Statement(), func(idx int, root, command Variables) {
+			if len(command) > 0 && command[0].Ident != nil {
+				calls = append(calls, FuncCall{
+					Name:  *command[0].Ident,
+					Ident: command[0],
+					Args:  command[1:],
+					Piped: idx > 0,
+					Pos:   command[0].Pos,
+				})
+			}
+		})
+	}
+	sort.SliceStable(calls, func(i, j int) bool {
+		return calls[i].Pos.Offset < calls[j].Pos.Offset
+	})
+	return
+}
+
+// ValidateFuncs checks the function calls within the given Tree against the
+// given template.FuncMap (text/template and html/template FuncMap values are
+// both accepted), reporting:
+//
+//   - "unknown-func" errors for calls of functions which are neither in the
+//     funcMap nor BuiltinFuncs
+//   - "arity" errors for calls with the wrong number of arguments, derived
+//     from the reflect signature of the funcMap entry
+//   - "invalid-func" errors for funcMap entries which are not functions or
+//     do not return one value, or one value and an error
+//   - "unused-func" warnings for funcMap entries which are never called
+func ValidateFuncs(tree Tree, funcMap map[string]any) (diagnostics Diagnostics) {
+	return validateFuncs(FuncCalls(tree), funcMap)
+}
+
+// ValidateFuncs is the Set equivalent of the package-level ValidateFuncs
+// function, where funcMap entries are unused when not called by any of the
+// Trees within this Set
+func (s *Set) ValidateFuncs(funcMap map[string]any) (diagnostics Diagnostics) {
+	var calls []FuncCall
+	s.WalkTrees(func(name string, tree Tree) (stop bool) {
+		calls = append(calls, FuncCalls(tree)...)
+		return
+	})
+	return validateFuncs(calls, funcMap)
+}
+
+var gErrorType = reflect.TypeOf((*error)(nil)).Elem()
+
+func validateFuncs(calls []FuncCall, funcMap map[string]any) (diagnostics Diagnostics) {
+	builtin := make(map[string]bool)
+	for _, name := range BuiltinFuncs {
+		builtin[name] = true
+	}
+
+	var names []string
+	for name := range funcMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	signatures := make(map[string]reflect.Type)
+	for _, name := range names {
+		ft := reflect.TypeOf(funcMap[name])
+		switch {
+		case ft == nil || ft.Kind() != reflect.Func:
+			diagnostics = append(diagnostics, Diagnostic{Severity: SeverityError, Code: "invalid-func", Message: fmt.Sprintf("%s is not a function", name)})
+			continue
+		case ft.NumOut() == 0 || ft.NumOut() > 2 || ft.NumOut() == 2 && ft.Out(1) != gErrorType:
+			diagnostics = append(diagnostics, Diagnostic{Severity: SeverityError, Code: "invalid-func", Message: fmt.Sprintf("%s must return one value, or one value and an error", name)})
+		}
+		signatures[name] = ft
+	}
+
+	called := make(map[string]bool)
+	for _, call := range calls {
+		called[call.Name] = true
+		ft, known := signatures[call.Name]
+		if !known {
+			if _, present := funcMap[call.Name]; !present && !builtin[call.Name] {
+				diagnostics = append(diagnostics, Diagnostic{Pos: call.Pos, Severity: SeverityError, Code: "unknown-func", Message: fmt.Sprintf("function %q not defined", call.Name)})
+			}
+			continue
+		}
+		count, want := call.NumArgs(), ft.NumIn()
+		if ft.IsVariadic() && count < want-1 {
+			diagnostics = append(diagnostics, Diagnostic{Pos: call.Pos, Severity: SeverityError, Code: "arity", Message: fmt.Sprintf("%s called with %d arguments; want at least %d", call.Name, count, want-1)})
+		} else if !ft.IsVariadic() && count != want {
+			diagnostics = append(diagnostics, Diagnostic{Pos: call.Pos, Severity: SeverityError, Code: "arity", Message: fmt.Sprintf("%s called with %d arguments; want %d", call.Name, count, want)})
+		}
+	}
+
+	for _, name := range names {
+		if !called[name] {
+			diagnostics = append(diagnostics, Diagnostic{Severity: SeverityWarning, Code: "unused-func", Message: fmt.Sprintf("function %q is never called", name)})
+		}
+	}
+
+	diagnostics.Sort()
+	return
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	htmlTemplate "html/template"
+	"strings"
+	"testing"
+	"testing/fstest"
+	textTemplate "text/template"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFuncCalls(t *testing.T) {
+
+	Convey("function position", t, func() {
+		tree, err := ParseTemplate("funcs.tmpl", `{{ .Name | printf "%s: %d" .Count | upper }}{{ if and (eq .x 1) (not .y) }}{{ end }}{{ $v := lower .z }}{{ .Field }}{{ print len }}`)
+		So(err, ShouldBeNil)
+		calls := FuncCalls(tree)
+		var names []string
+		for _, call := range calls {
+			names = append(names, call.Name)
+		}
+		So(names, ShouldEqual, []string{"printf", "upper", "and", "eq", "not", "lower", "print"})
+
+		So(calls[0].Piped, ShouldBeTrue)
+		So(calls[0].Args, ShouldHaveLength, 2)
+		So(calls[0].NumArgs(), ShouldEqual, 3)
+		So(calls[0].Pos.Offset, ShouldEqual, 11)
+		So(calls[1].NumArgs(), ShouldEqual, 1)
+		So(calls[2].Piped, ShouldBeFalse)
+		So(calls[2].NumArgs(), ShouldEqual, 2)
+		So(calls[3].NumArgs(), ShouldEqual, 2)
+		So(calls[5].NumArgs(), ShouldEqual, 1)
+		So(*calls[6].Args[0].Ident, ShouldEqual, "len")
+	})
+
+	Convey("ValidateFuncs", t, func() {
+		funcMap := textTemplate.FuncMap{
+			"upper":   strings.ToUpper,
+			"join":    strings.Join,
+			"concat":  func(values ...string) string { return strings.Join(values, "") },
+			"unused":  func() string { return "" },
+			"broken":  "not a function",
+			"nothing": func(string) {},
+			"safe":    func(v string) (string, error) { return v, nil },
+		}
+		tree, err := ParseTemplate("funcs.tmpl", `{{ upper .x }}{{ .x | upper }}{{ upper }}{{ join .list }}{{ concat }}{{ concat "a" "b" }}{{ missing .x }}{{ printf "%d" 1 }}{{ nothing "x" }}{{ safe "y" "z" }}`)
+		So(err, ShouldBeNil)
+
+		var lines []string
+		for _, d := range ValidateFuncs(tree, funcMap) {
+			lines = append(lines, d.String())
+		}
+		So(lines, ShouldEqual, []string{
+			"error: broken is not a function (invalid-func)",
+			"error: nothing must return one value, or one value and an error (invalid-func)",
+			`warning: function "broken" is never called (unused-func)`,
+			`warning: function "unused" is never called (unused-func)`,
+			"funcs.tmpl:1:34: error: upper called with 0 arguments; want 1 (arity)",
+			"funcs.tmpl:1:45: error: join called with 1 arguments; want 2 (arity)",
+			`funcs.tmpl:1:93: error: function "missing" not defined (unknown-func)`,
+			"funcs.tmpl:1:145: error: safe called with 2 arguments; want 1 (arity)",
+		})
+
+		// html/template FuncMap values are accepted too
+		So(ValidateFuncs(tree, htmlTemplate.FuncMap{}).HasErrors(), ShouldBeTrue)
+	})
+
+	Convey("Set.ValidateFuncs", t, func() {
+		set, err := LoadSet(fstest.MapFS{
+			"a.tmpl": {Data: []byte(`{{ upper .x }}`)},
+			"b.tmpl": {Data: []byte(`{{ lower .x }}`)},
+		}, "*.tmpl")
+		So(err, ShouldBeNil)
+		diagnostics := set.ValidateFuncs(map[string]any{"upper": strings.ToUpper, "lower": strings.ToLower})
+		So(diagnostics, ShouldBeEmpty)
+		So(diagnostics.HasErrors(), ShouldBeFalse)
+	})
+}
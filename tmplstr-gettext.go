@@ -0,0 +1,546 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultPOTHeader is the header message of catalogs created by NewPOT
+const DefaultPOTHeader = "Content-Type: text/plain; charset=UTF-8\n" +
+	"Content-Transfer-Encoding: 8bit\n"
+
+// POEntry is a single gettext catalog message
+//
+// See: https://www.gnu.org/software/gettext/manual/html_node/PO-Files.html
+type POEntry struct {
+	// TranslatorComments are the `# ` comment lines
+	TranslatorComments []string `json:"translator_comments,omitempty"`
+	// ExtractedComments are the `#.` comment lines
+	ExtractedComments []string `json:"extracted_comments,omitempty"`
+	// References are the `#:` source references, in file:line form. File
+	// names containing whitespace are rendered within the Unicode isolate
+	// characters U+2068 and U+2069, as GNU gettext does
+	References []string `json:"references,omitempty"`
+	// Flags are the `#,` flags, like "fuzzy"
+	Flags []string `json:"flags,omitempty"`
+	// Context is the msgctxt value
+	Context string `json:"context,omitempty"`
+	// ID is the msgid value, empty for the header entry
+	ID string `json:"id"`
+	// Plural is the msgid_plural value
+	Plural string `json:"plural,omitempty"`
+	// Str is the msgstr value, or the msgstr[n] values of plural entries
+	Str []string `json:"str"`
+	// Obsolete is true for `#~` entries no longer present in the sources
+	Obsolete bool `json:"obsolete,omitempty"`
+}
+
+// Key returns the Context and ID of this POEntry, uniquely identifying it
+// within a PO catalog
+func (e *POEntry) Key() (key string) {
+	if e.Context != "" {
+		return e.Context + "\x04" + e.ID
+	}
+	return e.ID
+}
+
+// IsHeader returns true if this is the header entry of a PO catalog
+func (e *POEntry) IsHeader() bool {
+	return e.ID == "" && e.Context == ""
+}
+
+// HasFlag returns true if this POEntry has the given flag
+func (e *POEntry) HasFlag(flag string) bool {
+	for _, f := range e.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// Render returns the PO source text of this POEntry
+func (e *POEntry) Render() (source string) {
+	var prefix string
+	if e.Obsolete {
+		prefix = "#~ "
+	}
+	for _, c := range e.TranslatorComments {
+		source += strings.TrimRight("# "+c, " ") + "\n"
+	}
+	for _, c := range e.ExtractedComments {
+		source += "#. " + c + "\n"
+	}
+	if len(e.References) > 0 {
+		source += "#:"
+		for _, ref := range e.References {
+			source += " " + poReference(ref)
+		}
+		source += "\n"
+	}
+	if len(e.Flags) > 0 {
+		source += "#, " + strings.Join(e.Flags, ", ") + "\n"
+	}
+	if e.Context != "" {
+		source += poQuote(prefix, "msgctxt", e.Context)
+	}
+	source += poQuote(prefix, "msgid", e.ID)
+	if e.Plural != "" {
+		source += poQuote(prefix, "msgid_plural", e.Plural)
+		str := e.Str
+		if len(str) == 0 {
+			str = []string{"", ""}
+		}
+		for idx, s := range str {
+			source += poQuote(prefix, "msgstr["+strconv.Itoa(idx)+"]", s)
+		}
+		return
+	}
+	var str string
+	if len(e.Str) > 0 {
+		str = e.Str[0]
+	}
+	source += poQuote(prefix, "msgstr", str)
+	return
+}
+
+// PO is a gettext catalog, either a template (.pot) or a translation (.po)
+type PO struct {
+	// Entries is the list of all messages, starting with the header entry
+	// when present
+	Entries []*POEntry `json:"entries"`
+}
+
+// NewPOT returns a new PO template with the given messages, as returned by
+// ExtractMessages. Messages with the same ID are combined into one POEntry
+// with all of their references and translator notes (as extracted comments)
+func NewPOT(messages []Message) (pot *PO) {
+	pot = &PO{Entries: []*POEntry{{Str: []string{DefaultPOTHeader}}}}
+	lookup := make(map[string]*POEntry)
+	for _, m := range messages {
+		if m.ID == "" {
+			continue
+		}
+		entry, present := lookup[m.ID]
+		if !present {
+			entry = &POEntry{ID: m.ID}
+			lookup[m.ID] = entry
+			pot.Entries = append(pot.Entries, entry)
+		}
+		if entry.Plural == "" && m.Plural != "" {
+			entry.Plural = m.Plural
+		}
+		if m.Comment != "" {
+			for _, line := range strings.Split(m.Comment, "\n") {
+				entry.ExtractedComments = appendUnique(entry.ExtractedComments, strings.TrimSpace(line))
+			}
+		}
+		if m.Pos.Filename != "" {
+			entry.References = appendUnique(entry.References, m.Pos.Filename+":"+strconv.Itoa(m.Pos.Line))
+		}
+	}
+	for _, entry := range pot.Entries[1:] {
+		if entry.Plural != "" {
+			entry.Str = []string{"", ""}
+		} else {
+			entry.Str = []string{""}
+		}
+	}
+	return
+}
+
+// Header returns the header entry of this PO, or nil if there is none
+func (p *PO) Header() (header *POEntry) {
+	if len(p.Entries) > 0 && p.Entries[0].IsHeader() {
+		header = p.Entries[0]
+	}
+	return
+}
+
+// Lookup returns the POEntry with the given context and ID, or nil if not
+// found
+func (p *PO) Lookup(context, id string) (entry *POEntry) {
+	key := (&POEntry{Context: context, ID: id}).Key()
+	for _, e := range p.Entries {
+		if e.Key() == key {
+			return e
+		}
+	}
+	return
+}
+
+// Render returns the PO source text of this catalog, with all obsolete
+// entries last
+func (p *PO) Render() (source string) {
+	var obsolete []*POEntry
+	for _, e := range p.Entries {
+		if e.Obsolete {
+			obsolete = append(obsolete, e)
+			continue
+		}
+		if source != "" {
+			source += "\n"
+		}
+		source += e.Render()
+	}
+	for _, e := range obsolete {
+		if source != "" {
+			source += "\n"
+		}
+		source += e.Render()
+	}
+	return
+}
+
+// MergePO updates the given translation catalog with the messages of the
+// given template catalog, like the gettext msgmerge tool without fuzzy
+// matching. Translations, translator comments and flags are kept from po
+// while references, extracted comments and plural forms are taken from pot.
+// Messages missing from pot are kept as obsolete entries and new messages
+// are added untranslated. Neither argument is modified
+func MergePO(po, pot *PO) (merged *PO) {
+	merged = &PO{}
+	if header := po.Header(); header != nil {
+		merged.Entries = append(merged.Entries, header.clone())
+	} else if header = pot.Header(); header != nil {
+		merged.Entries = append(merged.Entries, header.clone())
+	}
+
+	existing := make(map[string]*POEntry)
+	for _, e := range po.Entries {
+		if !e.IsHeader() {
+			existing[e.Key()] = e
+		}
+	}
+
+	seen := make(map[string]struct{})
+	for _, e := range pot.Entries {
+		if e.IsHeader() {
+			continue
+		}
+		entry := e.clone()
+		entry.Obsolete = false
+		if found, ok := existing[e.Key()]; ok {
+			entry.TranslatorComments = append([]string(nil), found.TranslatorComments...)
+			entry.Flags = append([]string(nil), found.Flags...)
+			entry.Str = append([]string(nil), found.Str...)
+		}
+		entry.Str = resizeStr(entry.Str, entry.Plural != "")
+		seen[e.Key()] = struct{}{}
+		merged.Entries = append(merged.Entries, entry)
+	}
+
+	for _, e := range po.Entries {
+		if _, ok := seen[e.Key()]; ok || e.IsHeader() {
+			continue
+		}
+		entry := e.clone()
+		entry.Obsolete = true
+		merged.Entries = append(merged.Entries, entry)
+	}
+	return
+}
+
+// ParsePO parses the given PO source text, returning an error with the line
+// number of any malformed lines
+func ParsePO(input string) (po *PO, err error) {
+	po = &PO{}
+	var entry *POEntry
+	var field *string
+	var seenID bool
+
+	finish := func() {
+		if entry != nil && seenID {
+			po.Entries = append(po.Entries, entry)
+		}
+		entry, field, seenID = nil, nil, false
+	}
+	current := func() *POEntry {
+		if entry == nil {
+			entry = &POEntry{}
+		}
+		return entry
+	}
+
+	for idx, line := range strings.Split(input, "\n") {
+		number := idx + 1
+		line = strings.TrimSpace(strings.TrimSuffix(line, "\r"))
+
+		if line == "" {
+			finish()
+			continue
+		}
+
+		obsolete := strings.HasPrefix(line, "#~")
+		if obsolete {
+			line = strings.TrimSpace(line[2:])
+		} else if strings.HasPrefix(line, "#") {
+			if seenID {
+				finish()
+			}
+			e := current()
+			switch {
+			case strings.HasPrefix(line, "#."):
+				e.ExtractedComments = append(e.ExtractedComments, strings.TrimSpace(line[2:]))
+			case strings.HasPrefix(line, "#:"):
+				e.References = append(e.References, poReferences(line[2:])...)
+			case strings.HasPrefix(line, "#,"):
+				for _, flag := range strings.Split(line[2:], ",") {
+					if flag = strings.TrimSpace(flag); flag != "" {
+						e.Flags = append(e.Flags, flag)
+					}
+				}
+			case strings.HasPrefix(line, "#|"):
+				// previous msgid values are not retained
+			default:
+				e.TranslatorComments = append(e.TranslatorComments, strings.TrimSpace(line[1:]))
+			}
+			continue
+		}
+
+		if strings.HasPrefix(line, `"`) {
+			if field == nil {
+				return nil, fmt.Errorf("line %d: unexpected string continuation", number)
+			}
+			var value string
+			if value, err = poUnquote(line); err != nil {
+				return nil, fmt.Errorf("line %d: %w", number, err)
+			}
+			*field += value
+			continue
+		}
+
+		keyword, rest, _ := strings.Cut(line, " ")
+		var value string
+		if value, err = poUnquote(strings.TrimSpace(rest)); err != nil {
+			return nil, fmt.Errorf("line %d: %w", number, err)
+		}
+
+		switch {
+		case keyword == "msgctxt":
+			if seenID {
+				finish()
+			}
+			e := current()
+			e.Context, field = value, &e.Context
+		case keyword == "msgid":
+			if seenID {
+				finish()
+			}
+			e := current()
+			e.ID, field, seenID = value, &e.ID, true
+		case keyword == "msgid_plural" && seenID:
+			entry.Plural, field = value, &entry.Plural
+		case keyword == "msgstr" && seenID:
+			entry.Str = append(entry.Str, value)
+			field = &entry.Str[len(entry.Str)-1]
+		case strings.HasPrefix(keyword, "msgstr[") && strings.HasSuffix(keyword, "]") && seenID:
+			var n int
+			if n, err = strconv.Atoi(keyword[7 : len(keyword)-1]); err != nil || n != len(entry.Str) {
+				return nil, fmt.Errorf("line %d: unexpected %s", number, keyword)
+			}
+			entry.Str = append(entry.Str, value)
+			field = &entry.Str[n]
+		default:
+			return nil, fmt.Errorf("line %d: unexpected %q", number, keyword)
+		}
+		entry.Obsolete = obsolete
+	}
+	finish()
+	return
+}
+
+func (e *POEntry) clone() (cloned *POEntry) {
+	cloned = &POEntry{
+		TranslatorComments: append([]string(nil), e.TranslatorComments...),
+		ExtractedComments:  append([]string(nil), e.ExtractedComments...),
+		References:         append([]string(nil), e.References...),
+		Flags:              append([]string(nil), e.Flags...),
+		Context:            e.Context,
+		ID:                 e.ID,
+		Plural:             e.Plural,
+		Str:                append([]string(nil), e.Str...),
+		Obsolete:           e.Obsolete,
+	}
+	return
+}
+
+// resizeStr returns the given msgstr values with at least the number of
+// values required for singular or plural entries
+func resizeStr(str []string, plural bool) []string {
+	switch {
+	case !plural && len(str) > 1:
+		return str[:1]
+	case !plural && len(str) == 0:
+		return []string{""}
+	case plural && len(str) < 2:
+		return append(str, make([]string, 2-len(str))...)
+	}
+	return str
+}
+
+// poQuote returns the PO source lines for the given keyword and value,
+// splitting multi-line values after each newline
+func poQuote(prefix, keyword, value string) (source string) {
+	lines := strings.SplitAfter(value, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) <= 1 {
+		return prefix + keyword + " " + poEscape(value) + "\n"
+	}
+	source = prefix + keyword + ` ""` + "\n"
+	for _, line := range lines {
+		source += prefix + poEscape(line) + "\n"
+	}
+	return
+}
+
+// poEscape returns the given value as a double-quoted PO string
+func poEscape(value string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range value {
+		switch r {
+		case '\\':
+			sb.WriteString(`\\`)
+		case '"':
+			sb.WriteString(`\"`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\r':
+			sb.WriteString(`\r`)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// poUnquote returns the value of the given double-quoted PO string. Only the
+// C escape sequences supported by GNU gettext are accepted, Go escapes like
+// \u and \x are errors
+func poUnquote(quoted string) (value string, err error) {
+	if len(quoted) < 2 || quoted[0] != '"' || quoted[len(quoted)-1] != '"' {
+		return "", fmt.Errorf("invalid string: %s", quoted)
+	}
+	var sb strings.Builder
+	for idx, inner := 0, quoted[1:len(quoted)-1]; idx < len(inner); idx++ {
+		c := inner[idx]
+		switch {
+		case c == '"':
+			return "", fmt.Errorf("invalid string: %s", quoted)
+		case c != '\\':
+			sb.WriteByte(c)
+			continue
+		case idx+1 == len(inner):
+			return "", fmt.Errorf("invalid string: %s", quoted)
+		}
+		idx += 1
+		switch c = inner[idx]; c {
+		case 'n':
+			sb.WriteByte('\n')
+		case 't':
+			sb.WriteByte('\t')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'a':
+			sb.WriteByte('\a')
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'v':
+			sb.WriteByte('\v')
+		case '"', '\\', '\'', '?':
+			sb.WriteByte(c)
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			// up to three octal digits
+			var n int
+			end := min(idx+3, len(inner))
+			for ; idx < end && inner[idx] >= '0' && inner[idx] <= '7'; idx++ {
+				n = n*8 + int(inner[idx]-'0')
+			}
+			if n > 0xff {
+				return "", fmt.Errorf("invalid escape \\%s: %s", inner[end-3:end], quoted)
+			}
+			sb.WriteByte(byte(n))
+			idx -= 1
+		default:
+			return "", fmt.Errorf("invalid escape \\%c: %s", c, quoted)
+		}
+	}
+	return sb.String(), nil
+}
+
+// poReference returns the given file:line reference as written on a `#:`
+// line, isolating file names containing whitespace
+func poReference(ref string) string {
+	if !strings.ContainsAny(ref, " \t") {
+		return ref
+	}
+	file, line := ref, ""
+	if idx := strings.LastIndexByte(ref, ':'); idx >= 0 {
+		if _, err := strconv.Atoi(ref[idx+1:]); err == nil {
+			file, line = ref[:idx], ref[idx:]
+		}
+	}
+	return "\u2068" + file + "\u2069" + line
+}
+
+// poReferences returns the references of a `#:` line, splitting on
+// whitespace outside of the U+2068 and U+2069 isolate characters
+func poReferences(line string) (refs []string) {
+	var sb strings.Builder
+	var isolated bool
+	for _, r := range line {
+		switch {
+		case r == '\u2068':
+			isolated = true
+		case r == '\u2069':
+			isolated = false
+		case !isolated && (r == ' ' || r == '\t'):
+			if sb.Len() > 0 {
+				refs = append(refs, sb.String())
+				sb.Reset()
+			}
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	if sb.Len() > 0 {
+		refs = append(refs, sb.String())
+	}
+	return
+}
+
+// appendUnique appends value to list unless it is empty or already present
+func appendUnique(list []string, value string) []string {
+	if value == "" {
+		return list
+	}
+	for _, v := range list {
+		if v == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
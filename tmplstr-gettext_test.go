@@ -0,0 +1,183 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGettext(t *testing.T) {
+
+	tPOT := func() *PO {
+		var messages []Message
+		for _, input := range [][2]string{
+			{"page.tmpl", "{{ _ \"Hello %s\" /* greeting */ .Name }}\n{{ __ \"%d item\" \"%d items\" .Count }}"},
+			{"other.tmpl", "\n\n{{ _ \"Hello %s\" /* the user name */ .User }}{{ _ \"Say \\\"hi\\\"\\n\" }}"},
+		} {
+			tree, err := ParseTemplate(input[0], input[1])
+			So(err, ShouldBeNil)
+			messages = append(messages, ExtractMessages(tree)...)
+		}
+		return NewPOT(messages)
+	}
+
+	expected := `msgid ""
+msgstr ""
+"Content-Type: text/plain; charset=UTF-8\n"
+"Content-Transfer-Encoding: 8bit\n"
+
+#. greeting
+#. the user name
+#: page.tmpl:1 other.tmpl:3
+msgid "Hello %s"
+msgstr ""
+
+#: page.tmpl:2
+msgid "%d item"
+msgid_plural "%d items"
+msgstr[0] ""
+msgstr[1] ""
+
+#: other.tmpl:3
+msgid "Say \"hi\"\n"
+msgstr ""
+`
+
+	Convey("NewPOT", t, func() {
+		pot := tPOT()
+		So(pot.Entries, ShouldHaveLength, 4)
+		So(pot.Header(), ShouldEqual, pot.Entries[0])
+		So(pot.Render(), ShouldEqual, expected)
+		So(pot.Lookup("", "%d item").Plural, ShouldEqual, "%d items")
+		So(pot.Lookup("", "missing"), ShouldBeNil)
+	})
+
+	Convey("ParsePO", t, func() {
+		po, err := ParsePO(expected)
+		So(err, ShouldBeNil)
+		So(po.Entries, ShouldResemble, tPOT().Entries)
+		So(po.Render(), ShouldEqual, expected)
+
+		input := "# translator note\n#, fuzzy, go-format\nmsgctxt \"menu\"\nmsgid \"\"\n\"Open\"\nmsgstr \"Ouvrir\"\n" +
+			"#~ msgid \"gone\"\n#~ msgstr \"parti\"\n"
+		po, err = ParsePO(input)
+		So(err, ShouldBeNil)
+		So(po.Entries, ShouldHaveLength, 2)
+		So(po.Header(), ShouldBeNil)
+		entry := po.Lookup("menu", "Open")
+		So(entry, ShouldNotBeNil)
+		So(entry.TranslatorComments, ShouldEqual, []string{"translator note"})
+		So(entry.HasFlag("fuzzy"), ShouldBeTrue)
+		So(entry.HasFlag("c-format"), ShouldBeFalse)
+		So(entry.Str, ShouldEqual, []string{"Ouvrir"})
+		So(po.Entries[1].Obsolete, ShouldBeTrue)
+		So(po.Entries[1].Str, ShouldEqual, []string{"parti"})
+
+		for _, invalid := range []string{
+			"\"orphan\"",
+			"msgid unquoted",
+			"msgid \"a\"\nmsgstr[1] \"b\"",
+			"msgstr \"no id\"",
+			"msgfoo \"x\"",
+			"msgid \"\\u00e9\"",
+			"msgid \"\\U000000e9\"",
+			"msgid \"\\xe9\"",
+			"msgid \"\\777\"",
+			"msgid \"a\"b\"",
+			"msgid \"a\\\"",
+		} {
+			_, err = ParsePO(invalid)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, "line ")
+		}
+
+		Convey("escapes", func() {
+			po, err := ParsePO(`msgid "a\n\t\r\"\\\'\101\0\303\251 é"` + "\nmsgstr \"\"\n")
+			So(err, ShouldBeNil)
+			So(po.Entries[0].ID, ShouldEqual, "a\n\t\r\"\\'A\x00é é")
+
+			entry := &POEntry{ID: "tab\tquote\" back\\ 'apos' é\nline", Str: []string{"x"}}
+			po, err = ParsePO(entry.Render())
+			So(err, ShouldBeNil)
+			So(po.Entries, ShouldResemble, []*POEntry{entry})
+		})
+
+		Convey("references", func() {
+			entry := &POEntry{ID: "x", Str: []string{""}, References: []string{"my page.tmpl:3", "other.tmpl:1", "no line.tmpl"}}
+			source := entry.Render()
+			So(source, ShouldStartWith, "#: \u2068my page.tmpl\u2069:3 other.tmpl:1 \u2068no line.tmpl\u2069\n")
+			po, err := ParsePO(source)
+			So(err, ShouldBeNil)
+			So(po.Entries, ShouldResemble, []*POEntry{entry})
+			So(po.Render(), ShouldEqual, source)
+		})
+	})
+
+	Convey("MergePO", t, func() {
+		po, err := ParsePO(`msgid ""
+msgstr "Language: fr\n"
+
+# keep me
+#, fuzzy
+#: old.tmpl:9
+msgid "Hello %s"
+msgstr "Bonjour %s"
+
+msgid "%d item"
+msgstr "%d article"
+
+msgid "Removed"
+msgstr "Supprimé"
+`)
+		So(err, ShouldBeNil)
+		pot := tPOT()
+		merged := MergePO(po, pot)
+
+		So(merged.Header().Str, ShouldEqual, []string{"Language: fr\n"})
+		So(merged.Entries, ShouldHaveLength, 5)
+
+		hello := merged.Lookup("", "Hello %s")
+		So(hello.Str, ShouldEqual, []string{"Bonjour %s"})
+		So(hello.TranslatorComments, ShouldEqual, []string{"keep me"})
+		So(hello.Flags, ShouldEqual, []string{"fuzzy"})
+		So(hello.References, ShouldEqual, []string{"page.tmpl:1", "other.tmpl:3"})
+		So(hello.ExtractedComments, ShouldEqual, []string{"greeting", "the user name"})
+
+		items := merged.Lookup("", "%d item")
+		So(items.Plural, ShouldEqual, "%d items")
+		So(items.Str, ShouldEqual, []string{"%d article", ""})
+
+		So(merged.Lookup("", "Say \"hi\"\n").Str, ShouldEqual, []string{""})
+
+		removed := merged.Lookup("", "Removed")
+		So(removed.Obsolete, ShouldBeTrue)
+		So(merged.Render(), ShouldEndWith, "\n#~ msgid \"Removed\"\n#~ msgstr \"Supprimé\"\n")
+
+		// inputs are unchanged
+		So(po.Lookup("", "Removed").Obsolete, ShouldBeFalse)
+		So(pot.Lookup("", "Hello %s").Str, ShouldEqual, []string{""})
+
+		// a missing header is taken from the template
+		merged = MergePO(&PO{}, pot)
+		So(merged.Header().Str, ShouldEqual, []string{DefaultPOTHeader})
+
+		// obsolete entries are revived
+		remerged := MergePO(MergePO(po, &PO{}), po)
+		So(remerged.Lookup("", "Removed").Obsolete, ShouldBeFalse)
+		So(remerged.Lookup("", "Removed").Str, ShouldEqual, []string{"Supprimé"})
+	})
+}
@@ -0,0 +1,325 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+// Definition is a single {{define}} or {{block}} template definition
+type Definition struct {
+	// Name is the template name defined
+	Name string `json:"name"`
+	// Block is true for {{block}} definitions
+	Block bool `json:"block,omitempty"`
+	// Action is the opening define or block Action
+	Action *Action `json:"-"`
+	// Pos is the position of the opening Action
+	Pos lexer.Position `json:"pos"`
+}
+
+// Reference is a single {{template}} or {{block}} call of a named template
+type Reference struct {
+	// Name is the template name called
+	Name string `json:"name"`
+	// From is the name of the definition containing this Reference, empty
+	// when at the top level of a file
+	From string `json:"from,omitempty"`
+	// Block is true for {{block}} calls
+	Block bool `json:"block,omitempty"`
+	// Action is the template or block Action
+	Action *Action `json:"-"`
+	// Pos is the position of the Action
+	Pos lexer.Position `json:"pos"`
+}
+
+// Source returns the graph node name of this Reference: the From name, or
+// the Pos.Filename when at the top level of a file
+func (r *Reference) Source() string {
+	if r.From != "" {
+		return r.From
+	}
+	return r.Pos.Filename
+}
+
+// DefinitionGraph describes the named template definitions within one or
+// more Trees and the references between them
+type DefinitionGraph struct {
+	// Definitions are all definitions, in source order
+	Definitions []*Definition `json:"definitions,omitempty"`
+	// References are all references, in source order
+	References []*Reference `json:"references,omitempty"`
+}
+
+// NewDefinitionGraph returns the DefinitionGraph of the given Trees. Any
+// Tree with mismatched control structures results in a *BlockError
+func NewDefinitionGraph(trees ...Tree) (g *DefinitionGraph, err error) {
+	g = &DefinitionGraph{}
+	for _, tree := range trees {
+		var blocks BlockTree
+		if blocks, err = tree.Structure(); err != nil {
+			return nil, err
+		}
+		g.add(blocks, "")
+	}
+	return
+}
+
+// DefinitionGraph returns the DefinitionGraph of all Trees within this Set,
+// in path order
+func (s *Set) DefinitionGraph() (g *DefinitionGraph, err error) {
+	var trees []Tree
+	for _, name := range s.Paths() {
+		trees = append(trees, s.Trees[name])
+	}
+	return NewDefinitionGraph(trees...)
+}
+
+func (g *DefinitionGraph) add(blocks BlockTree, from string) {
+	for _, node := range blocks {
+		if node.Branch != nil {
+			if action := node.Branch.Action; action != nil && action.Control() == "template" {
+				g.References = append(g.References, &Reference{Name: action.Statement().NameValue(), From: from, Action: action, Pos: action.Pos})
+			}
+			continue
+		} else if node.Block == nil {
+			continue
+		}
+		block, action := node.Block, node.Block.Open.Action
+		switch block.Keyword {
+		case "define", "block":
+			name := action.Statement().NameValue()
+			isBlock := block.Keyword == "block"
+			g.Definitions = append(g.Definitions, &Definition{Name: name, Block: isBlock, Action: action, Pos: action.Pos})
+			if isBlock {
+				g.References = append(g.References, &Reference{Name: name, From: from, Block: true, Action: action, Pos: action.Pos})
+			}
+			g.add(block.Body, name)
+		default:
+			g.add(block.Body, from)
+			for _, clause := range block.Else {
+				g.add(clause.Body, from)
+			}
+		}
+	}
+}
+
+// Names returns the sorted list of all defined template names
+func (g *DefinitionGraph) Names() (names []string) {
+	seen := make(map[string]bool)
+	for _, d := range g.Definitions {
+		if !seen[d.Name] {
+			seen[d.Name] = true
+			names = append(names, d.Name)
+		}
+	}
+	sort.Strings(names)
+	return
+}
+
+// Lookup returns all definitions of the given template name
+func (g *DefinitionGraph) Lookup(name string) (definitions []*Definition) {
+	for _, d := range g.Definitions {
+		if d.Name == name {
+			definitions = append(definitions, d)
+		}
+	}
+	return
+}
+
+// ReferencesTo returns all references calling the given template name
+func (g *DefinitionGraph) ReferencesTo(name string) (references []*Reference) {
+	for _, r := range g.References {
+		if r.Name == name {
+			references = append(references, r)
+		}
+	}
+	return
+}
+
+// ReferencesFrom returns all references within the given template name, or
+// within the top level of the given filename
+func (g *DefinitionGraph) ReferencesFrom(name string) (references []*Reference) {
+	for _, r := range g.References {
+		if r.Source() == name {
+			references = append(references, r)
+		}
+	}
+	return
+}
+
+// Undefined returns all references to template names without any definition
+func (g *DefinitionGraph) Undefined() (references []*Reference) {
+	for _, r := range g.References {
+		if len(g.Lookup(r.Name)) == 0 {
+			references = append(references, r)
+		}
+	}
+	return
+}
+
+// Duplicated returns the sorted list of template names with more than one
+// {{define}}. A single {{define}} overriding a {{block}} is not a duplicate
+func (g *DefinitionGraph) Duplicated() (names []string) {
+	counts := make(map[string]int)
+	for _, d := range g.Definitions {
+		if !d.Block {
+			counts[d.Name] += 1
+		}
+	}
+	for name, count := range counts {
+		if count > 1 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return
+}
+
+// Unused returns all {{define}} definitions which are never referenced
+func (g *DefinitionGraph) Unused() (definitions []*Definition) {
+	for _, d := range g.Definitions {
+		if !d.Block && len(g.ReferencesTo(d.Name)) == 0 {
+			definitions = append(definitions, d)
+		}
+	}
+	return
+}
+
+// Cycles returns each group of template names which reference each other,
+// directly or indirectly, including templates referencing themselves. Each
+// group is sorted and the groups are sorted by their first name
+func (g *DefinitionGraph) Cycles() (cycles [][]string) {
+	edges := make(map[string][]string)
+	var nodes []string
+	for _, r := range g.References {
+		if r.From == "" {
+			continue
+		}
+		if _, present := edges[r.From]; !present {
+			nodes = append(nodes, r.From)
+		}
+		edges[r.From] = append(edges[r.From], r.Name)
+	}
+
+	// Tarjan's strongly connected components
+	var index int
+	var stack []string
+	indexes, lowest, onStack := make(map[string]int), make(map[string]int), make(map[string]bool)
+	var connect func(name string)
+	connect = func(name string) {
+		indexes[name], lowest[name] = index, index
+		index += 1
+		stack = append(stack, name)
+		onStack[name] = true
+		var selfLoop bool
+		for _, next := range edges[name] {
+			if next == name {
+				selfLoop = true
+			}
+			if _, visited := indexes[next]; !visited {
+				connect(next)
+				lowest[name] = min(lowest[name], lowest[next])
+			} else if onStack[next] {
+				lowest[name] = min(lowest[name], indexes[next])
+			}
+		}
+		if lowest[name] != indexes[name] {
+			return
+		}
+		var component []string
+		for {
+			last := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[last] = false
+			component = append(component, last)
+			if last == name {
+				break
+			}
+		}
+		if len(component) > 1 || selfLoop {
+			sort.Strings(component)
+			cycles = append(cycles, component)
+		}
+	}
+	for _, name := range nodes {
+		if _, visited := indexes[name]; !visited {
+			connect(name)
+		}
+	}
+
+	sort.Slice(cycles, func(i, j int) bool {
+		return cycles[i][0] < cycles[j][0]
+	})
+	return
+}
+
+// DOT returns a Graphviz DOT description of this DefinitionGraph. Files are
+// drawn as note shapes, definitions as boxes (blocks are rounded) and
+// undefined template names as dashed boxes. File node IDs are prefixed with
+// "file:" so that files and templates with the same name remain distinct
+func (g *DefinitionGraph) DOT() (dot string) {
+	var sb strings.Builder
+	sb.WriteString("digraph templates {\n")
+
+	written := make(map[string]bool)
+	node := func(id, attributes string) {
+		if !written[id] {
+			written[id] = true
+			sb.WriteString("\t" + dotQuote(id) + " [" + attributes + "];\n")
+		}
+	}
+	source := func(r *Reference) (id string) {
+		if r.From != "" {
+			return r.From
+		}
+		return "file:" + r.Pos.Filename
+	}
+	for _, r := range g.References {
+		if r.From == "" {
+			node(source(r), "shape=note,label="+dotQuote(r.Pos.Filename))
+		}
+	}
+	for _, d := range g.Definitions {
+		if d.Block {
+			node(d.Name, "shape=box,style=rounded")
+		} else {
+			node(d.Name, "shape=box")
+		}
+	}
+	for _, r := range g.References {
+		node(r.Name, "shape=box,style=dashed")
+	}
+
+	edges := make(map[string]bool)
+	for _, r := range g.References {
+		edge := "\t" + dotQuote(source(r)) + " -> " + dotQuote(r.Name) + ";\n"
+		if !edges[edge] {
+			edges[edge] = true
+			sb.WriteString(edge)
+		}
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// dotQuote returns the given name as a double-quoted DOT ID, escaping only
+// double quotes and backslashes
+func dotQuote(name string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name) + `"`
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"testing"
+	"testing/fstest"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDefinitionGraph(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"base.tmpl": {Data: []byte(`<html>{{ block "content" . }}default{{ end }}{{ template "footer" . }}</html>`)},
+		"parts.tmpl": {Data: []byte(`{{ define "footer" }}{{ if .x }}{{ template "links" . }}{{ end }}{{ end }}` +
+			`{{ define "links" }}{{ range .Links }}{{ template "link" . }}{{ end }}{{ end }}` +
+			`{{ define "link" }}{{ template "links" .Children }}{{ end }}` +
+			`{{ define "loop" }}{{ template "loop" . }}{{ end }}` +
+			`{{ define "unused" }}{{ template "missing" }}{{ end }}`)},
+		"page.tmpl": {Data: []byte(`{{ define "content" }}page{{ end }}{{ define "unused" }}again{{ end }}`)},
+	}
+
+	Convey("NewDefinitionGraph", t, func() {
+		set, err := LoadSet(fsys, "*.tmpl")
+		So(err, ShouldBeNil)
+		g, err := set.DefinitionGraph()
+		So(err, ShouldBeNil)
+
+		So(g.Names(), ShouldEqual, []string{"content", "footer", "link", "links", "loop", "unused"})
+
+		content := g.Lookup("content")
+		So(content, ShouldHaveLength, 2)
+		So(content[0].Block, ShouldBeTrue)
+		So(content[0].Pos.Filename, ShouldEqual, "base.tmpl")
+		So(content[1].Block, ShouldBeFalse)
+		So(content[1].Pos.Filename, ShouldEqual, "page.tmpl")
+		So(g.Lookup("nope"), ShouldBeEmpty)
+
+		links := g.ReferencesTo("links")
+		So(links, ShouldHaveLength, 2)
+		So(links[0].From, ShouldEqual, "footer")
+		So(links[1].From, ShouldEqual, "link")
+
+		top := g.ReferencesFrom("base.tmpl")
+		So(top, ShouldHaveLength, 2)
+		So(top[0].Name, ShouldEqual, "content")
+		So(top[0].Block, ShouldBeTrue)
+		So(top[1].Name, ShouldEqual, "footer")
+		So(top[1].Source(), ShouldEqual, "base.tmpl")
+
+		undefined := g.Undefined()
+		So(undefined, ShouldHaveLength, 1)
+		So(undefined[0].Name, ShouldEqual, "missing")
+		So(undefined[0].From, ShouldEqual, "unused")
+
+		So(g.Duplicated(), ShouldEqual, []string{"unused"})
+
+		unused := g.Unused()
+		So(unused, ShouldHaveLength, 2)
+		So(unused[0].Pos.Filename, ShouldEqual, "page.tmpl")
+		So(unused[1].Pos.Filename, ShouldEqual, "parts.tmpl")
+
+		So(g.Cycles(), ShouldEqual, [][]string{{"link", "links"}, {"loop"}})
+	})
+
+	Convey("DOT", t, func() {
+		tree, err := ParseTemplate("page.tmpl", `{{ block "main" . }}{{ template "part" }}{{ end }}{{ define "part" }}{{ end }}{{ template "gone" }}`)
+		So(err, ShouldBeNil)
+		g, err := NewDefinitionGraph(tree)
+		So(err, ShouldBeNil)
+		So(g.DOT(), ShouldEqual, `digraph templates {
+	"file:page.tmpl" [shape=note,label="page.tmpl"];
+	"main" [shape=box,style=rounded];
+	"part" [shape=box];
+	"gone" [shape=box,style=dashed];
+	"file:page.tmpl" -> "main";
+	"main" -> "part";
+	"file:page.tmpl" -> "gone";
+}
+`)
+
+		// files and templates with the same name are separate nodes and
+		// names are quoted for DOT, not Go
+		tree, err = ParseTemplate("header", `{{ template "header" }}{{ define "header" }}{{ template "é\\x00\"q" }}{{ end }}`)
+		So(err, ShouldBeNil)
+		g, err = NewDefinitionGraph(tree)
+		So(err, ShouldBeNil)
+		So(g.DOT(), ShouldEqual, `digraph templates {
+	"file:header" [shape=note,label="header"];
+	"header" [shape=box];
+	"é\\x00\"q" [shape=box,style=dashed];
+	"file:header" -> "header";
+	"header" -> "é\\x00\"q";
+}
+`)
+	})
+
+	Convey("errors", t, func() {
+		tree, err := ParseTemplate("bad.tmpl", `{{ define "x" }}`)
+		So(err, ShouldBeNil)
+		_, err = NewDefinitionGraph(tree)
+		So(err, ShouldNotBeNil)
+		_, ok := err.(*BlockError)
+		So(ok, ShouldBeTrue)
+	})
+}
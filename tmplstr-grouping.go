@@ -14,14 +14,23 @@
 
 package tmplstr
 
+import (
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
 // Grouping represents a grouping Pipeline
 //
 // Example: in `{{ ident (inner pipeline) }}` the Grouping is the
-// `(inner pipeline)` portion
+// `(inner pipeline)` portion. Field chains applied to the Grouping result,
+// such as the `.Name` in `(index .x 0).Name`, are stored in Field
 type Grouping struct {
 	Open  *string   `parser:"( @GroupOpen"    json:"open,omitempty"`
 	Group *Pipeline `parser:"  @@"            json:"group,omitempty"`
 	Close *string   `parser:"  @GroupClose )" json:"close,omitempty"`
+	Field *string   `parser:"  @Field?"       json:"field,omitempty"`
+
+	Pos    lexer.Position `parser:"" json:"-"`
+	EndPos lexer.Position `parser:"" json:"-"`
 }
 
 // Render returns the source text represented by this Grouping
@@ -29,5 +38,17 @@ func (g Grouping) Render() (source string) {
 	source += *g.Open
 	source += g.Group.Render()
 	source += *g.Close
+	if g.Field != nil {
+		source += *g.Field
+	}
 	return
 }
+
+// offset updates the positions of this Grouping, and all of its child nodes,
+// to be relative to the given base position
+func (g *Grouping) offset(base lexer.Position) {
+	g.Pos, g.EndPos = base.Add(g.Pos), base.Add(g.EndPos)
+	if g.Group != nil {
+		g.Group.offset(base)
+	}
+}
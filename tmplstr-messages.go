@@ -0,0 +1,158 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+// DefaultMessageFuncs is the list of translation function specifications used
+// by ExtractMessages when none are given. The `_` function takes a singular
+// message and the `__` function takes a singular and a plural message
+var DefaultMessageFuncs = []string{"_", "__:plural"}
+
+// Message is a single translatable message found by ExtractMessages
+//
+// Example: `{{ _ "Hello %s" /* greeting */ .Name }}` is a Message with the
+// Func "_", the ID "Hello %s", the Comment "greeting" and the Args ".Name"
+type Message struct {
+	// Func is the name of the translation function called
+	Func string `json:"func"`
+	// ID is the unquoted message text
+	ID string `json:"id"`
+	// Plural is the unquoted plural message text, for plural functions
+	Plural string `json:"plural,omitempty"`
+	// Args are the source text of the remaining arguments
+	Args []string `json:"args,omitempty"`
+	// Comment is the translator note, from the inline comment bound to the
+	// message text or the leading comment-only actions (see BindComments)
+	Comment string `json:"comment,omitempty"`
+	// Pos is the position of the message text
+	Pos lexer.Position `json:"pos"`
+}
+
+// ExtractMessages returns all the translatable messages within the given
+// Tree, in source order, including those within nested Groupings and piped
+// Pipelines. A message is the first string argument of a command calling one
+// of the given translation functions (DefaultMessageFuncs when none are
+// given). Each function specification is a function name, optionally
+// followed by ":plural" when the second argument is the plural message
+//
+// Calls with a non-string message, such as `{{ _ .Key }}`, are skipped
+func ExtractMessages(tree Tree, funcs ...string) (messages []Message) {
+	if len(funcs) == 0 {
+		funcs = DefaultMessageFuncs
+	}
+	plurals := make(map[string]bool)
+	for _, spec := range funcs {
+		name, plural := strings.CutSuffix(spec, ":plural")
+		plurals[name] = plural
+	}
+
+	inline := make(map[*Variable]string)
+	leading := make(map[*Action]string)
+	for _, cb := range BindComments(tree) {
+		switch {
+		case cb.Leading && cb.Action != nil:
+			if text, present := leading[cb.Action]; present {
+				leading[cb.Action] = text + "\n" + cb.Text
+			} else {
+				leading[cb.Action] = cb.Text
+			}
+		case cb.Target != nil:
+			if _, present := inline[cb.Target]; !present {
+				inline[cb.Target] = cb.Text
+			}
+		}
+	}
+
+	for _, branch := range tree {
+		if branch.Action == nil || len(branch.Action.Pipelines) == 0 {
+			continue
+		}
+		action := branch.Action
+		walkCommands(action.Pipelines[0], action.Statement(), func(_ int, _, command Variables) {
+			if len(command) == 0 || command[0].Ident == nil {
+				return
+			}
+			plural, ok := plurals[*command[0].Ident]
+			if !ok {
+				return
+			}
+			if m, ok := extractMessage(command, plural, inline); ok {
+				if m.Comment == "" {
+					m.Comment = leading[action]
+				}
+				messages = append(messages, m)
+			}
+		})
+	}
+
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].Pos.Offset < messages[j].Pos.Offset
+	})
+	return
+}
+
+// extractMessage returns the Message of the given significant command, with
+// the translator note taken from the inline comments bound to the message
+// text
+func extractMessage(command Variables, plural bool, inline map[*Variable]string) (m Message, ok bool) {
+	if len(command) < 2 {
+		return
+	}
+	id := command[1]
+	if m.ID, ok = stringValue(id); !ok {
+		return
+	}
+	m.Func, m.Pos, m.Comment = *command[0].Ident, id.Pos, inline[id]
+	args := command[2:]
+	if plural && len(args) > 0 {
+		var isString bool
+		if m.Plural, isString = stringValue(args[0]); isString {
+			if m.Comment == "" {
+				m.Comment = inline[args[0]]
+			}
+			args = args[1:]
+		}
+	}
+	for _, v := range args {
+		m.Args = append(m.Args, v.Render())
+	}
+	return
+}
+
+// stringValue returns the unquoted value of String and Literal Variables
+func stringValue(v *Variable) (value string, ok bool) {
+	switch {
+	case v.String != nil:
+		return *v.String, true
+	case v.Literal != nil:
+		return *v.Literal, true
+	}
+	return
+}
+
+// commentText returns the text of the given comment, without the comment
+// markers and surrounding whitespace
+func commentText(comment string) (text string) {
+	text = strings.TrimPrefix(comment, "/*")
+	text = strings.TrimSuffix(text, "*/")
+	text = strings.TrimSpace(text)
+	return
+}
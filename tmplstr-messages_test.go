@@ -0,0 +1,93 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestExtractMessages(t *testing.T) {
+
+	extract := func(input string, funcs ...string) []Message {
+		tree, err := ParseTemplate("messages.tmpl", input)
+		So(err, ShouldBeNil)
+		return ExtractMessages(tree, funcs...)
+	}
+
+	Convey("singular messages", t, func() {
+		messages := extract(`before {{ _ "quoted text %q" $variable /* comment */ .Keyword }} after`)
+		So(messages, ShouldHaveLength, 1)
+		m := messages[0]
+		So(m.Func, ShouldEqual, "_")
+		So(m.ID, ShouldEqual, "quoted text %q")
+		So(m.Plural, ShouldEqual, "")
+		So(m.Args, ShouldEqual, []string{"$variable", ".Keyword"})
+		So(m.Comment, ShouldEqual, "")
+		So(m.Pos.Filename, ShouldEqual, "messages.tmpl")
+		So(m.Pos.Line, ShouldEqual, 1)
+		So(m.Pos.Offset, ShouldEqual, 12)
+
+		messages = extract("{{ _ \"thing\" /*\n  it's a comment\n*/ $var }}")
+		So(messages, ShouldHaveLength, 1)
+		So(messages[0].Comment, ShouldEqual, "it's a comment")
+		So(messages[0].Args, ShouldEqual, []string{"$var"})
+
+		messages = extract("{{ _ `raw text` }}{{ _ .Key }}{{ print \"not a message\" }}")
+		So(messages, ShouldHaveLength, 1)
+		So(messages[0].ID, ShouldEqual, "raw text")
+	})
+
+	Convey("plural messages", t, func() {
+		messages := extract(`{{ __ "%d item" /* count */ "%d items" .Count }}{{ __ "one" .Count }}`)
+		So(messages, ShouldHaveLength, 2)
+		So(messages[0].ID, ShouldEqual, "%d item")
+		So(messages[0].Plural, ShouldEqual, "%d items")
+		So(messages[0].Comment, ShouldEqual, "count")
+		So(messages[0].Args, ShouldEqual, []string{".Count"})
+		So(messages[1].ID, ShouldEqual, "one")
+		So(messages[1].Plural, ShouldEqual, "")
+		So(messages[1].Args, ShouldEqual, []string{".Count"})
+	})
+
+	Convey("groupings and pipelines", t, func() {
+		messages := extract(`{{ _ "first" $v /* one */ .K | other ( _ "second" /* two */ .K | other) | _ "third" }}`)
+		So(messages, ShouldHaveLength, 3)
+		So(messages[0].ID, ShouldEqual, "first")
+		So(messages[0].Comment, ShouldEqual, "")
+		So(messages[1].ID, ShouldEqual, "second")
+		So(messages[1].Comment, ShouldEqual, "two")
+		So(messages[2].ID, ShouldEqual, "third")
+
+		messages = extract("line one\n{{ if (_ \"nested\" (print (_ \"deeper\"))) }}{{ with $x := _ \"declared\" }}{{ end }}{{ end }}")
+		So(messages, ShouldHaveLength, 3)
+		So(messages[0].ID, ShouldEqual, "nested")
+		So(messages[0].Pos.Line, ShouldEqual, 2)
+		So(messages[1].ID, ShouldEqual, "deeper")
+		So(messages[2].ID, ShouldEqual, "declared")
+	})
+
+	Convey("custom functions", t, func() {
+		input := `{{ T "hello" }}{{ N "one" "many" 2 }}{{ _ "default" }}`
+		messages := extract(input, "T", "N:plural")
+		So(messages, ShouldHaveLength, 2)
+		So(messages[0].Func, ShouldEqual, "T")
+		So(messages[1].Func, ShouldEqual, "N")
+		So(messages[1].Plural, ShouldEqual, "many")
+		So(messages[1].Args, ShouldEqual, []string{"2"})
+		So(extract(input), ShouldHaveLength, 1)
+	})
+}
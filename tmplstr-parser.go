@@ -15,37 +15,155 @@
 package tmplstr
 
 import (
+	"fmt"
+	"regexp"
+	"strconv"
+
 	"github.com/alecthomas/participle/v2"
 	"github.com/alecthomas/participle/v2/lexer"
 )
 
 const (
-	gScalarPattern = `[a-zA-Z][_a-zA-Z0-9]*(\.[a-zA-Z][_a-zA-Z0-9]*)*`
+	gIdentPattern  = `[\p{L}_][\p{L}\p{Nd}_]*`
+	gNumberPattern = `[+-]?(?:` +
+		`0[xX][_0-9a-fA-F]*\.?[_0-9a-fA-F]*(?:[pP][+-]?[_0-9]+)?` +
+		`|0[bB][_01]+` +
+		`|0[oO][_0-7]+` +
+		`|(?:[0-9][_0-9]*\.?[_0-9]*|\.[0-9][_0-9]*)(?:[eE][+-]?[_0-9]+)?` +
+		`)i?`
 )
 
 var (
-	gTemplateLexer = lexer.MustSimple([]lexer.SimpleRule{
-		{Name: `Range`, Pattern: `range\s+(?:\$` + gScalarPattern + `)(?:\s*,\s*\$` + gScalarPattern + `)?\s+\:=`},
-		{Name: `Assignment`, Pattern: `\$` + gScalarPattern + `\s+:??=`},
-		{Name: `Ident`, Pattern: `[_a-zA-Z][_a-zA-Z0-9]*`},
-		{Name: `Keyword`, Pattern: `[.$]` + gScalarPattern},
-		{Name: `Literal`, Pattern: "`[^`]+?`"},
-		{Name: `String`, Pattern: `"(?:(\\"|[^"])+?)*"`},
-		{Name: `Rune`, Pattern: `'(?:(\\'|[^'])+?)+'`},
+	rxPlainInt   = regexp.MustCompile(`^(?:0|[1-9][0-9]*)$`)
+	rxPlainFloat = regexp.MustCompile(`^(?:0|[1-9][0-9]*)\.[0-9]+$`)
+)
+
+const (
+	// DefaultLeftDelim is the default left action delimiter
+	DefaultLeftDelim = "{{"
+	// DefaultRightDelim is the default right action delimiter
+	DefaultRightDelim = "}}"
+)
+
+var (
+	gDefaultParser = MustNewParser()
+)
+
+// Parser is a template parser configured with a specific pair of action
+// delimiters. The package-level functions such as ParseTemplate and
+// RemoveTemplateComments use a default Parser with the standard `{{` and `}}`
+// delimiters
+type Parser struct {
+	left   string
+	right  string
+	lexer  *lexer.StatefulDefinition
+	parser *participle.Parser[Action]
+}
+
+// ParserOption is a function for configuring a new Parser
+type ParserOption func(p *Parser) (err error)
+
+// WithDelims configures the Parser to use the given left and right action
+// delimiters, like text/template's Template.Delims. An empty delimiter means
+// the corresponding default is used. The `-` trim markers are supported with
+// custom delimiters the same as with the defaults, for example: `[[- ... -]]`
+func WithDelims(left, right string) ParserOption {
+	return func(p *Parser) (err error) {
+		if left == "" {
+			left = DefaultLeftDelim
+		}
+		if right == "" {
+			right = DefaultRightDelim
+		}
+		if left == right {
+			return fmt.Errorf("left and right delimiters are the same: %q", left)
+		}
+		p.left, p.right = left, right
+		return
+	}
+}
+
+// NewParser constructs a new Parser instance, configured with the given
+// options
+func NewParser(options ...ParserOption) (p *Parser, err error) {
+	p = &Parser{left: DefaultLeftDelim, right: DefaultRightDelim}
+	for _, option := range options {
+		if err = option(p); err != nil {
+			return nil, err
+		}
+	}
+	if p.lexer, err = lexer.NewSimple(makeLexerRules(p.left, p.right)); err != nil {
+		return nil, err
+	}
+	symbols := p.lexer.Symbols()
+	if p.parser, err = participle.Build[Action](
+		participle.Lexer(p.lexer),
+		participle.Map(func(token lexer.Token) (lexer.Token, error) {
+			// plain decimal numbers are parsed as Int and Float values, all
+			// other number spellings, including integers too large for an
+			// int, are kept as-is
+			if rxPlainInt.MatchString(token.Value) {
+				if _, err := strconv.ParseInt(token.Value, 10, strconv.IntSize); err != nil {
+					return token, nil
+				}
+				token.Type = symbols["Int"]
+			} else if rxPlainFloat.MatchString(token.Value) {
+				token.Type = symbols["Float"]
+			}
+			return token, nil
+		}, "Number"),
+		participle.Map(func(token lexer.Token) (lexer.Token, error) {
+			// raw strings have no escape sequences
+			token.Value = token.Value[1 : len(token.Value)-1]
+			return token, nil
+		}, "Literal"),
+		participle.Unquote("String", "Rune"),
+		participle.UseLookahead(1024),
+	); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// MustNewParser is a convenience wrapper around NewParser which panics on
+// error
+func MustNewParser(options ...ParserOption) (p *Parser) {
+	var err error
+	if p, err = NewParser(options...); err != nil {
+		panic(err)
+	}
+	return
+}
+
+// Delims returns the left and right action delimiters used by this Parser
+func (p *Parser) Delims() (left, right string) {
+	return p.left, p.right
+}
+
+func makeLexerRules(left, right string) []lexer.SimpleRule {
+	return []lexer.SimpleRule{
+		{Name: `StatementOpen`, Pattern: regexp.QuoteMeta(left) + `\-?`},
+		{Name: `StatementClose`, Pattern: `\-?` + regexp.QuoteMeta(right)},
+		{Name: `Range`, Pattern: `range\s+\$(?:` + gIdentPattern + `)?(?:\s*,\s*\$(?:` + gIdentPattern + `)?)?\s*:?=`},
+		{Name: `Assignment`, Pattern: `\$(?:` + gIdentPattern + `)?\s*:?=`},
+		{Name: `Bool`, Pattern: `(?:true|false)\b`},
+		{Name: `Nil`, Pattern: `nil\b`},
+		{Name: `Ident`, Pattern: gIdentPattern},
+		{Name: `Number`, Pattern: gNumberPattern},
+		{Name: `Keyword`, Pattern: `\$(?:` + gIdentPattern + `)?(?:\.` + gIdentPattern + `)*`},
+		{Name: `Field`, Pattern: `(?:\.` + gIdentPattern + `)+`},
+		{Name: `Dot`, Pattern: `\.`},
+		{Name: `Literal`, Pattern: "`[^`]*`"},
+		{Name: `String`, Pattern: `"(?:\\.|[^"\\])*"`},
+		{Name: `Rune`, Pattern: `'(?:\\.|[^'\\])+'`},
+		// plain decimal Number tokens are retyped as Float or Int tokens
 		{Name: `Float`, Pattern: `\d+\.\d+`},
 		{Name: `Int`, Pattern: `\d+`},
-		{Name: `Comment`, Pattern: `/\*(?:.+?)\*/`},
+		{Name: `Comment`, Pattern: `(?s)/\*.*?\*/`},
 		{Name: `Space`, Pattern: `\s+`},
 		{Name: `Pipe`, Pattern: `\|`},
 		{Name: `GroupOpen`, Pattern: `\(`},
 		{Name: `GroupClose`, Pattern: `\)`},
-		{Name: `StatementOpen`, Pattern: `\{\{\-?`},
-		{Name: `StatementClose`, Pattern: `\-?\}\}`},
 		{Name: `Text`, Pattern: `.+`},
-	})
-	gTemplateParser = participle.MustBuild[Action](
-		participle.Lexer(gTemplateLexer),
-		participle.Unquote("Literal", "String", "Rune"),
-		participle.UseLookahead(1024),
-	)
-)
+	}
+}
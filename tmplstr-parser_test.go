@@ -0,0 +1,94 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParser(t *testing.T) {
+
+	Convey("NewParser", t, func() {
+		p, err := NewParser()
+		So(err, ShouldBeNil)
+		left, right := p.Delims()
+		So(left, ShouldEqual, DefaultLeftDelim)
+		So(right, ShouldEqual, DefaultRightDelim)
+
+		p, err = NewParser(WithDelims("[[", ""))
+		So(err, ShouldBeNil)
+		left, right = p.Delims()
+		So(left, ShouldEqual, "[[")
+		So(right, ShouldEqual, DefaultRightDelim)
+
+		p, err = NewParser(WithDelims("%%", "%%"))
+		So(err, ShouldNotBeNil)
+		So(p, ShouldBeNil)
+		So(func() { MustNewParser(WithDelims("%%", "%%")) }, ShouldPanic)
+	})
+
+	Convey("WithDelims", t, func() {
+
+		for _, delims := range [][2]string{{"[[", "]]"}, {"<%", "%>"}, {"((", "))"}} {
+			left, right := delims[0], delims[1]
+			p := MustNewParser(WithDelims(left, right))
+
+			Convey(left+" "+right, func() {
+
+				Convey("ParseTemplate", func() {
+					input := `{{ not an action }} ` + left + `- if .x -` + right + ` a ` + left + `end` + right
+					tree, err := p.ParseTemplate("delims.tmpl", input)
+					So(err, ShouldBeNil)
+					So(tree, ShouldHaveLength, 4)
+					So(*tree[0].Text, ShouldEqual, `{{ not an action }} `)
+					So(*tree[1].Action.Open, ShouldEqual, left+"-")
+					So(*tree[1].Action.Close, ShouldEqual, "-"+right)
+					So(tree[1].Action.Control(), ShouldEqual, "if")
+					So(tree[3].Action.Control(), ShouldEqual, "end")
+					So(tree.Render(), ShouldEqual, input)
+					blocks, err := tree.Structure()
+					So(err, ShouldBeNil)
+					So(blocks, ShouldHaveLength, 2)
+					So(blocks[1].Block.Keyword, ShouldEqual, "if")
+				})
+
+				Convey("ParseTemplateTolerant", func() {
+					input := left + ` [ ` + right + ` ok ` + left + ` good ` + right
+					tree, err := p.ParseTemplateTolerant("delims.tmpl", input)
+					So(err, ShouldNotBeNil)
+					So(err.(ParseErrors), ShouldHaveLength, 1)
+					So(tree[0].Invalid, ShouldNotBeNil)
+					So(tree[2].Action, ShouldNotBeNil)
+					So(tree.Render(), ShouldEqual, input)
+				})
+
+				Convey("RemoveTemplateComments", func() {
+					So(p.RemoveTemplateComments(left+` _ "k" /* c */ `+right), ShouldEqual, left+` _ "k"  `+right)
+					So(p.RemoveTemplateComments(left+`- _ "k" /* c */ -`+right), ShouldEqual, left+`- _ "k"  -`+right)
+					So(p.RemoveTemplateComments(left+`/* c */`+right), ShouldEqual, left+`/* c */`+right)
+					So(p.RemoveTemplateComments(`{{ _ "k" /* c */ }}`), ShouldEqual, `{{ _ "k" /* c */ }}`)
+				})
+
+				Convey("PruneTemplateComments", func() {
+					pruned, err := p.PruneTemplateComments(left + ` _ "k" /* c */ ` + right)
+					So(err, ShouldBeNil)
+					So(pruned, ShouldEqual, left+` _ "k"  `+right)
+				})
+			})
+		}
+	})
+}
@@ -14,11 +14,18 @@
 
 package tmplstr
 
+import (
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
 // Pipeline defines the source text representing a list of Variables which may
 // also be piped into another Pipeline instance
 type Pipeline struct {
 	Root Variables `parser:"@@ ( @@ )*"    json:"variables,omitempty"`
 	Pipe *Pipeline `parser:"( Pipe @@ )?"  json:"piped,omitempty"`
+
+	Pos    lexer.Position `parser:"" json:"-"`
+	EndPos lexer.Position `parser:"" json:"-"`
 }
 
 // Render returns the source text represented by this Pipeline
@@ -39,3 +46,15 @@ func (p *Pipeline) WalkVariables(fn func(variables *Variables) (stop bool)) (sto
 	}
 	return
 }
+
+// offset updates the positions of this Pipeline, and all of its child nodes,
+// to be relative to the given base position
+func (p *Pipeline) offset(base lexer.Position) {
+	p.Pos, p.EndPos = base.Add(p.Pos), base.Add(p.EndPos)
+	for _, v := range p.Root {
+		v.offset(base)
+	}
+	if p.Pipe != nil {
+		p.Pipe.offset(base)
+	}
+}
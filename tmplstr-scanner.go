@@ -0,0 +1,120 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"errors"
+	"strings"
+)
+
+// scanAction looks for the first action within src delimited by the given
+// left and right delimiters, returning the text before the action, the inner
+// text of the action (without the delimiters) and the text after the action.
+// Comments, quoted strings, raw strings and runes within the action are
+// skipped over while looking for the right delimiter, so comments may span
+// multiple lines and contain quotes or delimiters
+//
+// When an action is opened but never closed, or a comment or quotation within
+// it is never terminated, found is false, before is the text before the
+// action, inner is the rest of src after the left delimiter and err describes
+// the problem
+func scanAction(src, left, right string) (before, inner, after string, found bool, err error) {
+	start := strings.Index(src, left)
+	if start < 0 {
+		return src, "", "", false, nil
+	}
+	for idx := start + len(left); idx < len(src); {
+		if strings.HasPrefix(src[idx:], right) {
+			return src[:start], src[start+len(left) : idx], src[idx+len(right):], true, nil
+		} else if next, ok := skipCommentOrQuoted(src, idx); !ok {
+			return src[:start], src[start+len(left):], "", false, unterminatedError(src, idx)
+		} else if next > idx {
+			idx = next
+		} else {
+			idx += 1
+		}
+	}
+	return src[:start], src[start+len(left):], "", false, errors.New("unclosed action")
+}
+
+// unterminatedError returns the error describing the unterminated comment or
+// quotation starting at src[idx], using the text/template error messages
+func unterminatedError(src string, idx int) (err error) {
+	switch {
+	case strings.HasPrefix(src[idx:], "/*"):
+		return errors.New("unclosed comment")
+	case src[idx] == '`':
+		return errors.New("unterminated raw quoted string")
+	case src[idx] == '\'':
+		return errors.New("unterminated character constant")
+	}
+	return errors.New("unterminated quoted string")
+}
+
+// skipCommentOrQuoted returns the index just past the comment, quoted string,
+// raw string or rune starting at src[idx], or idx itself if there is nothing
+// to skip. ok is false when the comment or quotation is not terminated
+func skipCommentOrQuoted(src string, idx int) (next int, ok bool) {
+	switch {
+	case strings.HasPrefix(src[idx:], "/*"):
+		if end := strings.Index(src[idx+2:], "*/"); end >= 0 {
+			return idx + 2 + end + 2, true
+		}
+		return idx, false
+	case src[idx] == '`':
+		if end := strings.IndexByte(src[idx+1:], '`'); end >= 0 {
+			return idx + 1 + end + 1, true
+		}
+		return idx, false
+	case src[idx] == '"' || src[idx] == '\'':
+		quote := src[idx]
+		for next = idx + 1; next < len(src); next++ {
+			switch src[next] {
+			case '\\':
+				next += 1
+			case '\n':
+				// quoted strings and runes cannot span lines
+				return idx, false
+			case quote:
+				return next + 1, true
+			}
+		}
+		return idx, false
+	}
+	return idx, true
+}
+
+// removeComments returns the given action inner text with all comments
+// removed, preserving any comment-like text within quoted strings, raw
+// strings and runes
+func removeComments(inner string) (cleaned string) {
+	var last int
+	for idx := 0; idx < len(inner); {
+		next, ok := skipCommentOrQuoted(inner, idx)
+		if !ok {
+			break
+		} else if next == idx {
+			idx += 1
+			continue
+		}
+		if strings.HasPrefix(inner[idx:], "/*") {
+			cleaned += inner[last:idx]
+			last = next
+		}
+		idx = next
+	}
+	cleaned += inner[last:]
+	return
+}
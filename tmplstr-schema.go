@@ -0,0 +1,419 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"encoding/json"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+// Shape is a node of the data structure inferred by InferSchema
+type Shape struct {
+	// Name is the field name, empty for the root Shape and Elem shapes
+	Name string `json:"name,omitempty"`
+	// Fields are the fields referenced, sorted by Name
+	Fields []*Shape `json:"fields,omitempty"`
+	// Elem is the shape of the values iterated over with range
+	Elem *Shape `json:"elem,omitempty"`
+	// Iterated is true when used as the pipeline of a range
+	Iterated bool `json:"iterated,omitempty"`
+	// Tested is true when used as the condition of an if or with, or as an
+	// argument to and, or and not
+	Tested bool `json:"tested,omitempty"`
+	// Printed is true when used as the only value of an action which
+	// outputs it
+	Printed bool `json:"printed,omitempty"`
+	// Positions are the positions of all references to this Shape
+	Positions []lexer.Position `json:"-"`
+}
+
+// Field returns the Shape of the named field, or nil when not referenced
+func (s *Shape) Field(name string) *Shape {
+	idx := sort.Search(len(s.Fields), func(i int) bool { return s.Fields[i].Name >= name })
+	if idx < len(s.Fields) && s.Fields[idx].Name == name {
+		return s.Fields[idx]
+	}
+	return nil
+}
+
+// field returns the Shape of the named field, adding it when not present
+func (s *Shape) field(name string) (field *Shape) {
+	idx := sort.Search(len(s.Fields), func(i int) bool { return s.Fields[i].Name >= name })
+	if idx < len(s.Fields) && s.Fields[idx].Name == name {
+		return s.Fields[idx]
+	}
+	field = &Shape{Name: name}
+	s.Fields = append(s.Fields, nil)
+	copy(s.Fields[idx+1:], s.Fields[idx:])
+	s.Fields[idx] = field
+	return
+}
+
+// elem returns the Elem Shape, adding it when not present
+func (s *Shape) elem() *Shape {
+	if s.Elem == nil {
+		s.Elem = &Shape{}
+	}
+	return s.Elem
+}
+
+// Paths returns the sorted list of all paths within this Shape, like
+// ".Page.Title" and ".Items[].Name"
+func (s *Shape) Paths() (paths []string) {
+	var walk func(prefix string, shape *Shape)
+	walk = func(prefix string, shape *Shape) {
+		for _, field := range shape.Fields {
+			path := prefix + "." + field.Name
+			paths = append(paths, path)
+			walk(path, field)
+		}
+		if shape.Elem != nil {
+			walk(prefix+"[]", shape.Elem)
+		}
+	}
+	walk("", s)
+	sort.Strings(paths)
+	return
+}
+
+// Lookup returns the Shape at the given path, as returned by Paths, or nil
+// when not present
+func (s *Shape) Lookup(path string) (shape *Shape) {
+	shape = s
+	for _, segment := range strings.Split(strings.TrimPrefix(path, "."), ".") {
+		name := strings.TrimRight(segment, "[]")
+		if name != "" {
+			if shape = shape.Field(name); shape == nil {
+				return nil
+			}
+		}
+		for count := (len(segment) - len(name)) / 2; count > 0; count-- {
+			if shape = shape.Elem; shape == nil {
+				return nil
+			}
+		}
+	}
+	return
+}
+
+// Schema is the result of InferSchema
+type Schema struct {
+	// Root is the Shape of the data the template is executed with
+	Root *Shape `json:"root"`
+	// Definitions are the Shapes of the data each {{define}} is executed
+	// with, keyed by template name
+	Definitions map[string]*Shape `json:"definitions,omitempty"`
+}
+
+// InferSchema infers the shape of the data the given Tree is executed with,
+// from all the field references like `.Page.Title`, `$.Site.Name` and
+// `$item.Name`. The dot is rebound by with, range and block actions, and
+// variables are bound to the shape of their declared value, following the
+// same scoping rules as AnalyzeScopes. The values returned by functions are
+// not known, so references through them are not included
+//
+// Trees with mismatched control structures result in a *BlockError
+func InferSchema(tree Tree) (schema *Schema, err error) {
+	var blocks BlockTree
+	if blocks, err = tree.Structure(); err != nil {
+		return nil, err
+	}
+	schema = &Schema{Root: &Shape{}}
+	schema.walk(blocks, &shapeScope{dot: schema.Root, root: schema.Root})
+	return
+}
+
+// shapeScope is the dot, `$` and variables in scope while inferring shapes
+type shapeScope struct {
+	dot  *Shape
+	root *Shape
+	vars map[string]*Shape
+}
+
+func (sc *shapeScope) clone() (cloned *shapeScope) {
+	cloned = &shapeScope{dot: sc.dot, root: sc.root, vars: make(map[string]*Shape, len(sc.vars))}
+	for name, shape := range sc.vars {
+		cloned.vars[name] = shape
+	}
+	return
+}
+
+// resolve returns the Shape referenced by the given Variable, or nil when it
+// is not a Dot or Keyword reference to a known shape
+func (sc *shapeScope) resolve(v *Variable) (shape *Shape) {
+	var path string
+	switch {
+	case v.Dot != nil:
+		shape = sc.dot
+	case v.Keyword != nil && strings.HasPrefix(*v.Keyword, "$"):
+		name := rxVariableName.FindString(*v.Keyword)
+		if name == "" {
+			shape, path = sc.root, (*v.Keyword)[1:]
+		} else {
+			shape, path = sc.vars[name], (*v.Keyword)[len(name):]
+		}
+	case v.Keyword != nil:
+		shape, path = sc.dot, *v.Keyword
+	}
+	if shape == nil {
+		return nil
+	}
+	for _, name := range strings.Split(path, ".") {
+		if name != "" {
+			shape = shape.field(name)
+		}
+	}
+	return
+}
+
+// operand returns the Shape of the given commands when they are a single
+// operand, like `.Page` or `$x.Name`, and not a function call or pipeline
+func (sc *shapeScope) operand(commands []Variables) (shape *Shape) {
+	if len(commands) == 1 && len(commands[0]) == 1 {
+		if v := commands[0][0]; v.Dot != nil || v.Keyword != nil {
+			return sc.resolve(v)
+		}
+	}
+	return
+}
+
+func (schema *Schema) walk(blocks BlockTree, sc *shapeScope) {
+	for _, node := range blocks {
+		switch {
+		case node.Block != nil:
+			block := node.Block
+			switch block.Keyword {
+			case "define":
+				name := block.Open.Action.Statement().NameValue()
+				if schema.Definitions == nil {
+					schema.Definitions = make(map[string]*Shape)
+				}
+				shape, present := schema.Definitions[name]
+				if !present {
+					shape = &Shape{}
+					schema.Definitions[name] = shape
+				}
+				schema.walk(block.Body, &shapeScope{dot: shape, root: shape})
+			case "block":
+				dot := schema.action(block.Open.Action, sc)
+				schema.walk(block.Body, &shapeScope{dot: dot, root: dot})
+			default:
+				inner := sc.clone()
+				inner.dot = schema.action(block.Open.Action, inner)
+				schema.walk(block.Body, inner)
+				for _, clause := range block.Else {
+					inner.dot = sc.dot
+					if dot := schema.action(clause.Open.Action, inner); clause.Keyword == "else with" {
+						inner.dot = dot
+					}
+					schema.walk(clause.Body, inner)
+				}
+			}
+		case node.Branch != nil && node.Branch.Action != nil:
+			schema.action(node.Branch.Action, sc)
+		}
+	}
+}
+
+// action records the shapes referenced by the given Action, updating any
+// variables declared, and returns the dot within the body of with, range and
+// block actions (or the current dot for all other actions)
+func (schema *Schema) action(action *Action, sc *shapeScope) (dot *Shape) {
+	dot = sc.dot
+	if len(action.Pipelines) == 0 {
+		return
+	}
+	s := action.Statement()
+
+	walkCommands(action.Pipelines[0], s, func(_ int, _, command Variables) {
+		tested := len(command) > 0 && command[0].Ident != nil
+		if tested {
+			switch *command[0].Ident {
+			case "and", "or", "not":
+			default:
+				tested = false
+			}
+		}
+		for _, v := range command {
+			if shape := sc.resolve(v); shape != nil {
+				shape.Positions = append(shape.Positions, v.Pos)
+				shape.Tested = shape.Tested || tested
+			}
+		}
+	})
+
+	shape := sc.operand(s.Commands)
+	switch s.Keyword {
+	case "if", "else if":
+		if shape != nil {
+			shape.Tested = true
+		}
+	case "with", "else with":
+		if shape != nil {
+			shape.Tested = true
+		}
+		dot = shape
+	case "range":
+		if shape != nil {
+			shape.Iterated = true
+			dot = shape.elem()
+		} else {
+			dot = nil
+		}
+	case "block":
+		dot = shape
+	case "":
+		if shape != nil && s.Declare == nil {
+			shape.Printed = true
+		}
+	}
+
+	if s.Declare != nil && !s.IsAssign {
+		if sc.vars == nil {
+			sc.vars = make(map[string]*Shape)
+		}
+		switch {
+		case s.Keyword == "range" && len(s.Decl) == 2:
+			sc.vars[s.Decl[0]], sc.vars[s.Decl[1]] = nil, dot
+		case s.Keyword == "range" && len(s.Decl) == 1:
+			sc.vars[s.Decl[0]] = dot
+		default:
+			for _, name := range s.Decl {
+				sc.vars[name] = shape
+			}
+		}
+	}
+	return
+}
+
+// JSONSchema returns a JSON Schema (draft 2020-12) document describing this
+// Shape. Iterated shapes are arrays and shapes with fields are objects, the
+// types of all other values are not known and are left unconstrained with a
+// description of how they are used
+func (s *Shape) JSONSchema() (data []byte, err error) {
+	document := s.jsonSchema()
+	document["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	return json.MarshalIndent(document, "", "  ")
+}
+
+func (s *Shape) jsonSchema() (document map[string]any) {
+	document = make(map[string]any)
+	switch {
+	case s.Iterated && len(s.Fields) == 0:
+		document["type"] = "array"
+	case !s.Iterated && len(s.Fields) > 0:
+		document["type"] = "object"
+	}
+	if s.Elem != nil {
+		document["items"] = s.Elem.jsonSchema()
+	}
+	if len(s.Fields) > 0 {
+		properties := make(map[string]any)
+		for _, field := range s.Fields {
+			properties[field.Name] = field.jsonSchema()
+		}
+		document["properties"] = properties
+	}
+	var usage []string
+	for _, use := range []struct {
+		flag bool
+		name string
+	}{{s.Iterated, "iterated"}, {s.Tested, "tested"}, {s.Printed, "printed"}} {
+		if use.flag {
+			usage = append(usage, use.name)
+		}
+	}
+	if len(usage) > 0 {
+		document["description"] = strings.Join(usage, ", ")
+	}
+	return
+}
+
+// GoStruct returns the source code of a Go type declaration, with the given
+// type name, describing this Shape. Fields are exported struct fields, with
+// json tags when the template field name is not exported, and values with
+// unknown types are declared as `any`. When exporting a template field name
+// collides with another field, like `.name` and `.Name`, a number is appended
+// to the exported name, so `.name` is declared as `Name2` with a json tag
+func (s *Shape) GoStruct(typeName string) (source string, err error) {
+	var sb strings.Builder
+	sb.WriteString("type " + typeName + " ")
+	s.goType(&sb)
+	sb.WriteString("\n")
+	var formatted []byte
+	if formatted, err = format.Source([]byte(sb.String())); err != nil {
+		return "", err
+	}
+	return string(formatted), nil
+}
+
+func (s *Shape) goType(sb *strings.Builder) {
+	switch {
+	case s.Iterated || s.Elem != nil && len(s.Fields) == 0:
+		sb.WriteString("[]")
+		if s.Elem != nil {
+			s.Elem.goType(sb)
+		} else {
+			sb.WriteString("any")
+		}
+	case len(s.Fields) > 0:
+		sb.WriteString("struct {\n")
+		// template field names which are already exported keep their names
+		taken := make(map[string]struct{})
+		for _, field := range s.Fields {
+			if goFieldName(field.Name) == field.Name {
+				taken[field.Name] = struct{}{}
+			}
+		}
+		for _, field := range s.Fields {
+			name := goFieldName(field.Name)
+			if name != field.Name {
+				// numbered to not collide with fields like .Name and .name
+				unique := name
+				for n := 2; ; n++ {
+					if _, present := taken[unique]; !present {
+						break
+					}
+					unique = name + strconv.Itoa(n)
+				}
+				name = unique
+				taken[name] = struct{}{}
+			}
+			sb.WriteString(name + " ")
+			field.goType(sb)
+			if name != field.Name {
+				sb.WriteString(" `json:\"" + field.Name + "\"`")
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString("}")
+	default:
+		sb.WriteString("any")
+	}
+}
+
+// goFieldName returns the given template field name with the first letter in
+// upper case
+func goFieldName(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(r)) + name[size:]
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInferSchema(t *testing.T) {
+
+	infer := func(input string) *Schema {
+		tree, err := ParseTemplate("schema.tmpl", input)
+		So(err, ShouldBeNil)
+		schema, err := InferSchema(tree)
+		So(err, ShouldBeNil)
+		return schema
+	}
+
+	const page = `<title>{{ .Page.Title }}</title>
+{{ if .Page.Draft }}draft{{ end }}
+{{ with .Site }}{{ .Name }}{{ $.Page.Author.Name }}{{ end }}
+{{ range $i, $item := .Items }}{{ $item.Name }}{{ .Price | printf "%.2f" }}{{ else }}{{ .Empty }}{{ end }}
+{{ $tags := .Page.Tags }}{{ range $tags }}{{ . }}{{ end }}
+{{ if and .Flags.a (not .Flags.b) }}{{ end }}
+{{ block "footer" .Footer }}{{ .Copyright }}{{ $.Year }}{{ end }}
+{{ define "card" }}{{ .Heading }}{{ end }}
+{{ upper .Page.Title | print }}`
+
+	Convey("dot rebinding", t, func() {
+		schema := infer(page)
+		So(schema.Root.Paths(), ShouldEqual, []string{
+			".Empty",
+			".Flags",
+			".Flags.a",
+			".Flags.b",
+			".Footer",
+			".Footer.Copyright",
+			".Footer.Year",
+			".Items",
+			".Items[].Name",
+			".Items[].Price",
+			".Page",
+			".Page.Author",
+			".Page.Author.Name",
+			".Page.Draft",
+			".Page.Tags",
+			".Page.Title",
+			".Site",
+			".Site.Name",
+		})
+		So(schema.Definitions, ShouldHaveLength, 1)
+		So(schema.Definitions["card"].Paths(), ShouldEqual, []string{".Heading"})
+
+		root := schema.Root
+		title := root.Lookup(".Page.Title")
+		So(title.Printed, ShouldBeTrue)
+		So(title.Positions, ShouldHaveLength, 2)
+		So(title.Positions[0].Line, ShouldEqual, 1)
+		So(title.Positions[1].Line, ShouldEqual, 9)
+		So(root.Lookup(".Page.Draft").Tested, ShouldBeTrue)
+		So(root.Lookup(".Page.Draft").Printed, ShouldBeFalse)
+		So(root.Lookup(".Site").Tested, ShouldBeTrue)
+		So(root.Lookup(".Items").Iterated, ShouldBeTrue)
+		So(root.Lookup(".Items[].Name").Printed, ShouldBeTrue)
+		So(root.Lookup(".Items[].Price").Printed, ShouldBeFalse)
+		So(root.Lookup(".Page.Tags").Iterated, ShouldBeTrue)
+		So(root.Lookup(".Page.Tags[]").Printed, ShouldBeTrue)
+		So(root.Lookup(".Flags.a").Tested, ShouldBeTrue)
+		So(root.Lookup(".Flags.b").Tested, ShouldBeTrue)
+		So(root.Lookup(".Missing"), ShouldBeNil)
+		So(root.Lookup(".Page.Title[]"), ShouldBeNil)
+	})
+
+	Convey("JSONSchema", t, func() {
+		schema := infer(`{{ .Title }}{{ range .Items }}{{ .Name }}{{ end }}{{ range .Tags }}{{ end }}`)
+		data, err := schema.Root.JSONSchema()
+		So(err, ShouldBeNil)
+		var document map[string]any
+		So(json.Unmarshal(data, &document), ShouldBeNil)
+		So(document["$schema"], ShouldEqual, "https://json-schema.org/draft/2020-12/schema")
+		So(document["type"], ShouldEqual, "object")
+		properties := document["properties"].(map[string]any)
+		So(properties["Title"], ShouldResemble, map[string]any{"description": "printed"})
+		items := properties["Items"].(map[string]any)
+		So(items["type"], ShouldEqual, "array")
+		So(items["description"], ShouldEqual, "iterated")
+		So(items["items"], ShouldResemble, map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"Name": map[string]any{"description": "printed"}},
+		})
+		So(properties["Tags"], ShouldResemble, map[string]any{"type": "array", "description": "iterated", "items": map[string]any{}})
+	})
+
+	Convey("GoStruct", t, func() {
+		schema := infer(`{{ .Title }}{{ range .Items }}{{ .Name }}{{ .price }}{{ end }}{{ range .Tags }}{{ end }}{{ with .Meta }}{{ .Author }}{{ end }}`)
+		source, err := schema.Root.GoStruct("PageData")
+		So(err, ShouldBeNil)
+		So(source, ShouldEqual, "type PageData struct {\n"+
+			"\tItems []struct {\n"+
+			"\t\tName  any\n"+
+			"\t\tPrice any `json:\"price\"`\n"+
+			"\t}\n"+
+			"\tMeta struct {\n"+
+			"\t\tAuthor any\n"+
+			"\t}\n"+
+			"\tTags  []any\n"+
+			"\tTitle any\n"+
+			"}\n")
+
+		Convey("colliding field names", func() {
+			schema := infer(`{{ .name }}{{ .Name }}{{ .name2 }}{{ .Name2 }}`)
+			source, err := schema.Root.GoStruct("Data")
+			So(err, ShouldBeNil)
+			So(source, ShouldEqual, "type Data struct {\n"+
+				"\tName   any\n"+
+				"\tName2  any\n"+
+				"\tName3  any `json:\"name\"`\n"+
+				"\tName22 any `json:\"name2\"`\n"+
+				"}\n")
+		})
+	})
+
+	Convey("mismatched structures", t, func() {
+		tree, err := ParseTemplate("schema.tmpl", `{{ with .x }}`)
+		So(err, ShouldBeNil)
+		_, err = InferSchema(tree)
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,211 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/alecthomas/participle/v2/lexer"
+)
+
+var (
+	rxVariableName = regexp.MustCompile(`^\$` + gIdentPattern)
+)
+
+// VarDecl is a single variable declaration, like `$x :=` or one of the names
+// in `range $i, $e :=`
+type VarDecl struct {
+	// Name is the variable name, including the leading `$`
+	Name string `json:"name"`
+	// Declare is the Assign or Range Variable declaring the variable
+	Declare *Variable `json:"-"`
+	// Pos is the position of the variable name within the Declare Variable
+	Pos lexer.Position `json:"pos"`
+	// Uses are all references to this variable, including assignments
+	Uses []*VarUse `json:"-"`
+	// Shadows is the previous declaration of the same name still in scope
+	Shadows *VarDecl `json:"-"`
+}
+
+// Used returns true if this variable is referenced other than by assignments
+func (d *VarDecl) Used() bool {
+	for _, use := range d.Uses {
+		if !use.Assign {
+			return true
+		}
+	}
+	return false
+}
+
+// VarUse is a single reference to a variable, like `$x.Field` or the `$x` in
+// `$x = 1`
+type VarUse struct {
+	// Name is the variable name, including the leading `$`
+	Name string `json:"name"`
+	// Variable is the Keyword, Assign or Range Variable referencing it
+	Variable *Variable `json:"-"`
+	// Pos is the position of the variable name
+	Pos lexer.Position `json:"pos"`
+	// Assign is true for `=` assignments
+	Assign bool `json:"assign,omitempty"`
+	// Decl is the declaration referenced, nil when undeclared
+	Decl *VarDecl `json:"-"`
+}
+
+// ScopeAnalysis is the result of AnalyzeScopes
+type ScopeAnalysis struct {
+	// Decls are all variable declarations, in source order
+	Decls []*VarDecl `json:"decls,omitempty"`
+	// Uses are all variable references, in source order, not including the
+	// predeclared `$` variable
+	Uses []*VarUse `json:"uses,omitempty"`
+	// Diagnostics are all problems found, in source order
+	Diagnostics Diagnostics `json:"diagnostics,omitempty"`
+}
+
+// AnalyzeScopes resolves every variable reference within the given Tree to
+// its declaration, following the scoping rules of text/template: a variable
+// declared in an action is in scope until the {{end}} of the if, range or
+// with structure containing the action, or until the end of the template.
+// Variables declared within the opening action of a control structure (or
+// an {{else if}} or {{else with}} clause) are in scope for the rest of that
+// structure. The body of each {{define}} and {{block}} is a separate template
+// where only `$` is predeclared
+//
+// The Diagnostics reported are:
+//
+//   - "undefined-var" errors for references to undeclared variables
+//   - "assign-undeclared" errors for `=` assignments to undeclared variables
+//   - "shadowed-var" warnings for declarations of a name already in scope
+//   - "unused-var" warnings for variables which are never referenced
+//
+// Trees with mismatched control structures result in a *BlockError
+func AnalyzeScopes(tree Tree) (analysis *ScopeAnalysis, err error) {
+	var blocks BlockTree
+	if blocks, err = tree.Structure(); err != nil {
+		return nil, err
+	}
+	analysis = &ScopeAnalysis{}
+	analysis.walk(blocks, nil)
+
+	for _, decl := range analysis.Decls {
+		if !decl.Used() {
+			analysis.Diagnostics = append(analysis.Diagnostics, Diagnostic{Pos: decl.Pos, Severity: SeverityWarning, Code: "unused-var", Message: fmt.Sprintf("%s declared and not used", decl.Name)})
+		}
+	}
+	sort.SliceStable(analysis.Decls, func(i, j int) bool {
+		return analysis.Decls[i].Pos.Offset < analysis.Decls[j].Pos.Offset
+	})
+	sort.SliceStable(analysis.Uses, func(i, j int) bool {
+		return analysis.Uses[i].Pos.Offset < analysis.Uses[j].Pos.Offset
+	})
+	analysis.Diagnostics.Sort()
+	return
+}
+
+// walk analyses the given BlockTree with the given variables in scope and
+// returns the variables in scope afterwards
+func (sa *ScopeAnalysis) walk(blocks BlockTree, vars []*VarDecl) []*VarDecl {
+	for _, node := range blocks {
+		switch {
+		case node.Block != nil:
+			block := node.Block
+			switch block.Keyword {
+			case "define", "block":
+				sa.action(block.Open.Action, vars)
+				sa.walk(block.Body, nil)
+			default:
+				inner := sa.action(block.Open.Action, vars)
+				inner = sa.walk(block.Body, inner)
+				for _, clause := range block.Else {
+					inner = sa.action(clause.Open.Action, inner)
+					inner = sa.walk(clause.Body, inner)
+				}
+			}
+		case node.Branch != nil && node.Branch.Action != nil:
+			vars = sa.action(node.Branch.Action, vars)
+		}
+	}
+	return vars
+}
+
+// action analyses the given Action with the given variables in scope and
+// returns the variables in scope afterwards
+func (sa *ScopeAnalysis) action(action *Action, vars []*VarDecl) []*VarDecl {
+	if action == nil {
+		return vars
+	}
+	lookup := func(name string) *VarDecl {
+		for idx := len(vars) - 1; idx >= 0; idx-- {
+			if vars[idx].Name == name {
+				return vars[idx]
+			}
+		}
+		return nil
+	}
+
+	// like text/template, names are declared before the pipeline is parsed
+	s := action.Statement()
+	if s.Declare != nil {
+		token := s.Declare.Render()
+		for _, loc := range rxDeclaredVariable.FindAllStringIndex(token, -1) {
+			name := token[loc[0]:loc[1]]
+			pos := s.Declare.Pos
+			pos.Advance(token[:loc[0]])
+			previous := lookup(name)
+			if s.IsAssign {
+				use := &VarUse{Name: name, Variable: s.Declare, Pos: pos, Assign: true, Decl: previous}
+				sa.Uses = append(sa.Uses, use)
+				if previous == nil {
+					sa.Diagnostics = append(sa.Diagnostics, Diagnostic{Pos: pos, Severity: SeverityError, Code: "assign-undeclared", Message: fmt.Sprintf("assignment to undeclared variable %s", name)})
+				} else {
+					previous.Uses = append(previous.Uses, use)
+				}
+				continue
+			}
+			decl := &VarDecl{Name: name, Declare: s.Declare, Pos: pos, Shadows: previous}
+			if previous != nil {
+				sa.Diagnostics = append(sa.Diagnostics, Diagnostic{Pos: pos, Severity: SeverityWarning, Code: "shadowed-var", Message: fmt.Sprintf("declaration of %s shadows declaration at %v", name, previous.Pos)})
+			}
+			sa.Decls = append(sa.Decls, decl)
+			vars = append(vars[:len(vars):len(vars)], decl)
+		}
+	}
+
+	action.WalkVariables(func(variables *Variables) (stop bool) {
+		for _, v := range *variables {
+			if v.Keyword == nil {
+				continue
+			}
+			name := rxVariableName.FindString(*v.Keyword)
+			if name == "" {
+				// `$` and `$.Field` refer to the predeclared `$`, and fields
+				// like `.Field` are not variables
+				continue
+			}
+			use := &VarUse{Name: name, Variable: v, Pos: v.Pos, Decl: lookup(name)}
+			sa.Uses = append(sa.Uses, use)
+			if use.Decl == nil {
+				sa.Diagnostics = append(sa.Diagnostics, Diagnostic{Pos: v.Pos, Severity: SeverityError, Code: "undefined-var", Message: fmt.Sprintf("undefined variable %s", name)})
+			} else {
+				use.Decl.Uses = append(use.Decl.Uses, use)
+			}
+		}
+		return
+	})
+	return vars
+}
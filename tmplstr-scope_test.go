@@ -0,0 +1,118 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAnalyzeScopes(t *testing.T) {
+
+	analyze := func(input string) *ScopeAnalysis {
+		tree, err := ParseTemplate("scope.tmpl", input)
+		So(err, ShouldBeNil)
+		analysis, err := AnalyzeScopes(tree)
+		So(err, ShouldBeNil)
+		return analysis
+	}
+
+	codes := func(analysis *ScopeAnalysis) (found []string) {
+		for _, d := range analysis.Diagnostics {
+			found = append(found, d.Code+" "+d.Pos.String())
+		}
+		return
+	}
+
+	Convey("declarations and uses", t, func() {
+		analysis := analyze(`{{ $x := .a }}{{ range $i, $e := .list }}{{ $i }}{{ $e.Name }}{{ end }}{{ $x = 2 }}{{ $x }}`)
+		So(analysis.Diagnostics, ShouldBeEmpty)
+		So(analysis.Decls, ShouldHaveLength, 3)
+		So(analysis.Decls[0].Name, ShouldEqual, "$x")
+		So(analysis.Decls[0].Pos.Offset, ShouldEqual, 3)
+		So(analysis.Decls[1].Name, ShouldEqual, "$i")
+		So(analysis.Decls[1].Pos.Offset, ShouldEqual, 23)
+		So(analysis.Decls[2].Name, ShouldEqual, "$e")
+		So(analysis.Decls[2].Pos.Offset, ShouldEqual, 27)
+
+		x := analysis.Decls[0]
+		So(x.Uses, ShouldHaveLength, 2)
+		So(x.Uses[0].Assign, ShouldBeTrue)
+		So(x.Uses[1].Assign, ShouldBeFalse)
+		So(x.Used(), ShouldBeTrue)
+
+		So(analysis.Uses, ShouldHaveLength, 4)
+		So(analysis.Uses[1].Name, ShouldEqual, "$e")
+		So(*analysis.Uses[1].Variable.Keyword, ShouldEqual, "$e.Name")
+		So(analysis.Uses[1].Decl, ShouldEqual, analysis.Decls[2])
+	})
+
+	Convey("diagnostics", t, func() {
+		analysis := analyze(`{{ if .a }}{{ $y := 1 }}{{ else }}{{ $y }}{{ end }}{{ $y }}{{ $z = 1 }}{{ $ }}{{ $.Site }}`)
+		So(codes(analysis), ShouldEqual, []string{
+			"undefined-var scope.tmpl:1:55",
+			"assign-undeclared scope.tmpl:1:63",
+		})
+
+		analysis = analyze(`{{ $x := 1 }}{{ with $x := .a }}{{ $x }}{{ end }}{{ $unused := 2 }}`)
+		So(codes(analysis), ShouldEqual, []string{
+			"unused-var scope.tmpl:1:4",
+			"shadowed-var scope.tmpl:1:22",
+			"unused-var scope.tmpl:1:53",
+		})
+		So(analysis.Decls[1].Shadows, ShouldEqual, analysis.Decls[0])
+	})
+
+	Convey("control structures", t, func() {
+		analysis := analyze(`{{ if $a := .a }}{{ $a }}{{ else if $b := .b }}{{ $a }}{{ $b }}{{ else }}{{ $b }}{{ end }}{{ $a }}`)
+		So(codes(analysis), ShouldEqual, []string{"undefined-var scope.tmpl:1:94"})
+
+		analysis = analyze(`{{ $x := 1 }}{{ define "t" }}{{ $x }}{{ $ }}{{ end }}{{ block "b" $x }}{{ $x }}{{ end }}`)
+		So(codes(analysis), ShouldEqual, []string{
+			"undefined-var scope.tmpl:1:33",
+			"undefined-var scope.tmpl:1:75",
+		})
+	})
+
+	Convey("text/template parity", t, func() {
+		// text/template only reports `{{ $x = 1 }}` when executed, so
+		// assign-undeclared errors are not included here
+		for _, input := range []string{
+			`{{ $x := 1 }}{{ $x }}`,
+			`{{ $x }}`,
+			`{{ if true }}{{ $x := 1 }}{{ end }}{{ $x }}`,
+			`{{ if true }}{{ $x := 1 }}{{ else }}{{ $x }}{{ end }}`,
+			`{{ range $i := .l }}{{ end }}{{ $i }}`,
+			`{{ with $v := . }}{{ else with $w := $v }}{{ $w }}{{ end }}`,
+			`{{ $x := 1 }}{{ define "d" }}{{ $x }}{{ end }}`,
+			`{{ $x := (print $x) }}`,
+			`{{ print (printf "%v" $q) }}`,
+		} {
+			Convey(input, func() {
+				_, stdErr := tParseStd("parity", input, 0)
+				analysis := analyze(input)
+				So(analysis.Diagnostics.HasErrors(), ShouldEqual, stdErr != nil)
+			})
+		}
+	})
+
+	Convey("mismatched structures", t, func() {
+		tree, err := ParseTemplate("scope.tmpl", `{{ if .x }}`)
+		So(err, ShouldBeNil)
+		_, err = AnalyzeScopes(tree)
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,205 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Set is a collection of Trees parsed from multiple template files, keyed by
+// their slash-separated path within the fs.FS they were loaded from
+type Set struct {
+	// Trees are the parsed template files, including those with Invalid
+	// branches
+	Trees map[string]Tree
+	// Errors are the errors encountered reading or parsing each template
+	// file, parsing errors are ParseErrors
+	Errors map[string]error
+}
+
+// LoadSet parses all files within fsys matching any of the given patterns
+// concurrently, using ParseTemplateTolerant so that every readable file has
+// a Tree. Patterns are path.Match patterns matched against the whole path,
+// except those starting with "**/" which match the trailing path segments of
+// files at any depth. For example: "**/*.tmpl" matches all .tmpl files
+//
+// The returned error joins all the Set.Errors, in path order, and is also
+// returned when any pattern does not match any files
+func LoadSet(fsys fs.FS, patterns ...string) (set *Set, err error) {
+	return gDefaultParser.LoadSet(fsys, patterns...)
+}
+
+// LoadSet is the Parser equivalent of the package-level LoadSet function,
+// using this Parser's action delimiters
+func (p *Parser) LoadSet(fsys fs.FS, patterns ...string) (set *Set, err error) {
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("no patterns given")
+	}
+	for _, pattern := range patterns {
+		if _, err = path.Match(strings.TrimPrefix(pattern, "**/"), ""); err != nil {
+			return nil, fmt.Errorf("%w: %s", err, pattern)
+		}
+	}
+
+	var paths []string
+	matched := make(map[string]bool)
+	if err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		} else if d.IsDir() {
+			return nil
+		}
+		var found bool
+		for _, pattern := range patterns {
+			if setMatch(pattern, name) {
+				found, matched[pattern] = true, true
+			}
+		}
+		if found {
+			paths = append(paths, name)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	for _, pattern := range patterns {
+		if !matched[pattern] {
+			return nil, fmt.Errorf("pattern matches no files: %s", pattern)
+		}
+	}
+
+	set = &Set{
+		Trees:  make(map[string]Tree),
+		Errors: make(map[string]error),
+	}
+
+	var m sync.Mutex
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, runtime.GOMAXPROCS(0))
+	for _, name := range paths {
+		wg.Add(1)
+		limit <- struct{}{}
+		go func(name string) {
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+			tree, ee := p.loadFile(fsys, name)
+			m.Lock()
+			defer m.Unlock()
+			if tree != nil {
+				set.Trees[name] = tree
+			}
+			if ee != nil {
+				set.Errors[name] = ee
+			}
+		}(name)
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, name := range paths {
+		if ee, ok := set.Errors[name]; ok {
+			errs = append(errs, ee)
+		}
+	}
+	err = errors.Join(errs...)
+	return
+}
+
+func (p *Parser) loadFile(fsys fs.FS, name string) (tree Tree, err error) {
+	var data []byte
+	if data, err = fs.ReadFile(fsys, name); err != nil {
+		return
+	}
+	tree, err = p.ParseTemplateTolerant(name, string(data))
+	return
+}
+
+// setMatch reports whether the given path matches the LoadSet pattern
+func setMatch(pattern, name string) (matched bool) {
+	if rest, ok := strings.CutPrefix(pattern, "**/"); ok {
+		segments := strings.Split(name, "/")
+		for idx := range segments {
+			if matched, _ = path.Match(rest, strings.Join(segments[idx:], "/")); matched {
+				return
+			}
+		}
+		return
+	}
+	matched, _ = path.Match(pattern, name)
+	return
+}
+
+// Paths returns the paths of all Trees within this Set, in sorted order
+func (s *Set) Paths() (paths []string) {
+	for name := range s.Trees {
+		paths = append(paths, name)
+	}
+	sort.Strings(paths)
+	return
+}
+
+// Lookup returns the Tree of the given path
+func (s *Set) Lookup(name string) (tree Tree, ok bool) {
+	tree, ok = s.Trees[name]
+	return
+}
+
+// Render returns the source text of the Tree with the given path, or an
+// empty string if there is no such Tree
+func (s *Set) Render(name string) (source string) {
+	if tree, ok := s.Trees[name]; ok {
+		source = tree.Render()
+	}
+	return
+}
+
+// WalkTrees calls the given function for each Tree within this Set, in path
+// order, until it returns true
+func (s *Set) WalkTrees(fn func(name string, tree Tree) (stop bool)) (stopped bool) {
+	for _, name := range s.Paths() {
+		if stopped = fn(name, s.Trees[name]); stopped {
+			return
+		}
+	}
+	return
+}
+
+// WalkVariables walks all Trees within this Set, in path order, calling the
+// given VariablesWalkFn for all Variables present. The Pos.Filename of each
+// Variable is the path of the Tree it is within
+func (s *Set) WalkVariables(fn VariablesWalkFn) (stopped bool) {
+	return s.WalkTrees(func(name string, tree Tree) (stop bool) {
+		return tree.WalkVariables(fn)
+	})
+}
+
+// ExtractMessages returns the ExtractMessages results of all Trees within
+// this Set, in path order
+func (s *Set) ExtractMessages(funcs ...string) (messages []Message) {
+	s.WalkTrees(func(name string, tree Tree) (stop bool) {
+		messages = append(messages, ExtractMessages(tree, funcs...)...)
+		return
+	})
+	return
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLoadSet(t *testing.T) {
+
+	fsys := fstest.MapFS{
+		"layouts/base.html":         {Data: []byte(`<title>{{ _ "Title" }}</title>{{ template "content" . }}`)},
+		"layouts/partials/nav.tmpl": {Data: []byte(`{{ range .Links }}{{ .Name }}{{ end }}`)},
+		"pages/index.tmpl":          {Data: []byte(`{{ _ "Welcome" /* greeting */ }}`)},
+		"pages/broken.tmpl":         {Data: []byte(`ok {{ ( }} {{ .x }}`)},
+		"README.md":                 {Data: []byte(`{{ not a template }}`)},
+	}
+
+	Convey("patterns", t, func() {
+		set, err := LoadSet(fsys, "**/*.tmpl", "layouts/*.html")
+		So(err, ShouldNotBeNil)
+		So(set.Paths(), ShouldEqual, []string{
+			"layouts/base.html",
+			"layouts/partials/nav.tmpl",
+			"pages/broken.tmpl",
+			"pages/index.tmpl",
+		})
+
+		set, err = LoadSet(fsys, "pages/index.tmpl")
+		So(err, ShouldBeNil)
+		So(set.Paths(), ShouldEqual, []string{"pages/index.tmpl"})
+
+		set, err = LoadSet(fsys, "**/partials/*.tmpl")
+		So(err, ShouldBeNil)
+		So(set.Paths(), ShouldEqual, []string{"layouts/partials/nav.tmpl"})
+
+		_, err = LoadSet(fsys, "**/*.gohtml")
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "pattern matches no files: **/*.gohtml")
+
+		_, err = LoadSet(fsys, "[")
+		So(err, ShouldNotBeNil)
+
+		_, err = LoadSet(fsys)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("errors", t, func() {
+		set, err := LoadSet(fsys, "pages/*")
+		So(err, ShouldNotBeNil)
+		var pe *ParseError
+		So(errors.As(err, &pe), ShouldBeTrue)
+		So(pe.Pos.Filename, ShouldEqual, "pages/broken.tmpl")
+		So(set.Errors, ShouldHaveLength, 1)
+		So(set.Errors["pages/broken.tmpl"], ShouldNotBeNil)
+
+		broken, ok := set.Lookup("pages/broken.tmpl")
+		So(ok, ShouldBeTrue)
+		So(broken[1].Invalid, ShouldNotBeNil)
+		So(set.Render("pages/broken.tmpl"), ShouldEqual, `ok {{ ( }} {{ .x }}`)
+		So(set.Render("missing.tmpl"), ShouldEqual, "")
+	})
+
+	Convey("walking", t, func() {
+		set, err := LoadSet(fsys, "**/*.html", "pages/index.tmpl", "layouts/partials/*")
+		So(err, ShouldBeNil)
+
+		var names []string
+		So(set.WalkTrees(func(name string, tree Tree) (stop bool) {
+			names = append(names, name)
+			return len(names) == 2
+		}), ShouldBeTrue)
+		So(names, ShouldEqual, []string{"layouts/base.html", "layouts/partials/nav.tmpl"})
+
+		var files []string
+		set.WalkVariables(func(variables *Variables) (stop bool) {
+			for _, v := range *variables {
+				if v.Keyword != nil && *v.Keyword == ".Name" {
+					files = append(files, v.Pos.Filename)
+				}
+			}
+			return
+		})
+		So(files, ShouldEqual, []string{"layouts/partials/nav.tmpl"})
+
+		messages := set.ExtractMessages()
+		So(messages, ShouldHaveLength, 2)
+		So(messages[0].ID, ShouldEqual, "Title")
+		So(messages[0].Pos.Filename, ShouldEqual, "layouts/base.html")
+		So(messages[1].ID, ShouldEqual, "Welcome")
+		So(messages[1].Comment, ShouldEqual, "greeting")
+	})
+
+	Convey("os.DirFS", t, func() {
+		set, err := MustNewParser().LoadSet(os.DirFS("testdata"), "conformance/*.tmpl")
+		So(err, ShouldBeNil)
+		So(set.Paths(), ShouldContain, "conformance/control.tmpl")
+	})
+}
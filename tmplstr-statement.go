@@ -0,0 +1,144 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	rxDeclaredVariable = regexp.MustCompile(`\$(?:` + gIdentPattern + `)?`)
+)
+
+// Statement is the significant structure of an Action or Grouping pipeline,
+// with all Space and Comment Variables removed
+//
+// Example: `{{- range $i, $e := .List | sort /* note */ -}}` is a Statement
+// with the Keyword "range", the Decl names "$i" and "$e" and two Commands:
+// `.List` and `sort`
+type Statement struct {
+	// Keyword is the control keyword starting the Statement, see
+	// Action.Control
+	Keyword string
+	// Name is the name argument of template, define and block Statements
+	Name *Variable
+	// Declare is the Assign or Range Variable declaring the Decl names
+	Declare *Variable
+	// Decl is the list of variable names declared or assigned
+	Decl []string
+	// IsAssign is true when the Decl names are assigned with `=` instead of
+	// declared with `:=`
+	IsAssign bool
+	// Commands is the list of pipe-separated commands, each with only the
+	// significant Variables present
+	Commands []Variables
+}
+
+// Statement returns the Statement structure of this Action
+func (a *Action) Statement() (s *Statement) {
+	s = &Statement{Keyword: a.Control()}
+	if len(a.Pipelines) > 0 && a.Pipelines[0] != nil {
+		s.parse(a.Pipelines[0].Commands())
+	}
+	return
+}
+
+// Statement returns the Statement structure of this Grouping pipeline
+func (g *Grouping) Statement() (s *Statement) {
+	s = &Statement{}
+	if g.Group != nil {
+		s.parse(g.Group.Commands())
+	}
+	return
+}
+
+func (s *Statement) parse(commands []Variables) {
+	if len(commands) == 0 {
+		return
+	}
+	first := commands[0]
+
+	// skip the keyword
+	switch s.Keyword {
+	case "":
+	case "range":
+		if len(first) > 0 && first[0].Range != nil {
+			s.Declare = first[0]
+			s.Decl = rxDeclaredVariable.FindAllString(*first[0].Range, -1)
+			s.IsAssign = !strings.Contains(*first[0].Range, ":=")
+		}
+		first = first[1:]
+	case "else if", "else with":
+		first = first[2:]
+	default:
+		first = first[1:]
+	}
+
+	// take the name
+	switch s.Keyword {
+	case "template", "define", "block":
+		if len(first) > 0 && (first[0].String != nil || first[0].Literal != nil) {
+			s.Name = first[0]
+			first = first[1:]
+		}
+	}
+
+	// take the declarations
+	if len(first) > 0 && first[0].Assign != nil {
+		s.Declare = first[0]
+		s.Decl = rxDeclaredVariable.FindAllString(*first[0].Assign, -1)
+		s.IsAssign = !strings.Contains(*first[0].Assign, ":=")
+		first = first[1:]
+	}
+
+	if len(first) > 0 || len(commands) > 1 {
+		s.Commands = append([]Variables{first}, commands[1:]...)
+	}
+}
+
+// Commands returns the list of pipe-separated commands within this Pipeline,
+// each with all Space and Comment Variables removed
+func (p *Pipeline) Commands() (commands []Variables) {
+	for pipe := p; pipe != nil; pipe = pipe.Pipe {
+		commands = append(commands, pipe.Root.Significant())
+	}
+	return
+}
+
+// Significant returns this list of Variables without any Space or Comment
+// Variables
+func (vs Variables) Significant() (significant Variables) {
+	significant = Variables{}
+	for _, v := range vs {
+		if v.Space == nil && v.Comment == nil {
+			significant = append(significant, v)
+		}
+	}
+	return
+}
+
+// NameValue returns the unquoted name argument of template, define and block
+// Statements, or an empty string if there is none
+func (s *Statement) NameValue() (name string) {
+	if s.Name != nil {
+		if s.Name.String != nil {
+			return *s.Name.String
+		} else if s.Name.Literal != nil {
+			return *s.Name.Literal
+		}
+	}
+	return
+}
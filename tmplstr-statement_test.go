@@ -0,0 +1,75 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestStatement(t *testing.T) {
+	Convey("Action.Statement", t, func() {
+		statement := func(input string) *Statement {
+			tree, err := ParseTemplate("statement.tmpl", input)
+			So(err, ShouldBeNil)
+			return tree[0].Action.Statement()
+		}
+
+		s := statement(`{{- range $i, $e := .List | sort /* note */ -}}`)
+		So(s.Keyword, ShouldEqual, "range")
+		So(s.Decl, ShouldEqual, []string{"$i", "$e"})
+		So(s.IsAssign, ShouldBeFalse)
+		So(*s.Declare.Range, ShouldEqual, "range $i, $e :=")
+		So(s.Commands, ShouldHaveLength, 2)
+		So(*s.Commands[0][0].Keyword, ShouldEqual, ".List")
+		So(*s.Commands[1][0].Ident, ShouldEqual, "sort")
+
+		s = statement(`{{ $x = printf "%d" 1 }}`)
+		So(s.Keyword, ShouldEqual, "")
+		So(s.Decl, ShouldEqual, []string{"$x"})
+		So(s.IsAssign, ShouldBeTrue)
+		So(s.Commands, ShouldHaveLength, 1)
+		So(s.Commands[0], ShouldHaveLength, 3)
+
+		s = statement(`{{ else if $v := .x }}`)
+		So(s.Keyword, ShouldEqual, "else if")
+		So(s.Decl, ShouldEqual, []string{"$v"})
+		So(*s.Commands[0][0].Keyword, ShouldEqual, ".x")
+
+		s = statement("{{ template `name` . }}")
+		So(s.Keyword, ShouldEqual, "template")
+		So(s.NameValue(), ShouldEqual, "name")
+		So(*s.Commands[0][0].Dot, ShouldEqual, ".")
+
+		s = statement(`{{ define "name" }}`)
+		So(s.NameValue(), ShouldEqual, "name")
+		So(s.Commands, ShouldBeNil)
+
+		s = statement(`{{ end }}`)
+		So(s.Keyword, ShouldEqual, "end")
+		So(s.Commands, ShouldBeNil)
+		So(s.NameValue(), ShouldEqual, "")
+
+		s = statement(`{{/* comment */}}`)
+		So(s.Commands, ShouldBeNil)
+
+		tree, err := ParseTemplate("statement.tmpl", `{{ print (len .x | add 1) }}`)
+		So(err, ShouldBeNil)
+		gs := tree[0].Action.Pipelines[0].Root[3].Grouping.Statement()
+		So(gs.Commands, ShouldHaveLength, 2)
+		So(*gs.Commands[1][0].Ident, ShouldEqual, "add")
+	})
+}
@@ -50,3 +50,26 @@ func (t Tree) WalkVariables(fn VariablesWalkFn) (stopped bool) {
 	}
 	return
 }
+
+// Structure folds this flat Tree into a BlockTree where each control
+// structure (if, range, with, define and block) is a Block node containing
+// its body, any else clauses and the matching {{end}}. Mismatched, unexpected
+// or missing {{end}} and {{else}} actions are reported as a *BlockError
+func (t Tree) Structure() (blocks BlockTree, err error) {
+	if blocks, err = t.structure(); err != nil {
+		blocks = nil
+	}
+	return
+}
+
+// BranchAt returns the Branch containing the given byte offset, or nil when
+// the offset is outside of this Tree. The Pos of text/template/parse nodes
+// are byte offsets and can be used to find the corresponding Branch
+func (t Tree) BranchAt(offset int) (branch *Branch) {
+	for _, b := range t {
+		if b.Pos.Offset <= offset && offset < b.EndPos.Offset {
+			return b
+		}
+	}
+	return
+}
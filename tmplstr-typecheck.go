@@ -0,0 +1,413 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	gBoolType    = reflect.TypeOf(false)
+	gIntType     = reflect.TypeOf(0)
+	gFloatType   = reflect.TypeOf(0.0)
+	gRuneType    = reflect.TypeOf(' ')
+	gStringType  = reflect.TypeOf("")
+	gBuiltinType = map[string]reflect.Type{
+		"eq": gBoolType, "ge": gBoolType, "gt": gBoolType, "le": gBoolType,
+		"lt": gBoolType, "ne": gBoolType, "not": gBoolType, "len": gIntType,
+		"html": gStringType, "js": gStringType, "urlquery": gStringType,
+		"print": gStringType, "printf": gStringType, "println": gStringType,
+	}
+)
+
+// TypeCheck verifies the given Tree against the type of the data it is to be
+// executed with, and the function signatures of the given template.FuncMap
+// (text/template and html/template FuncMap values are both accepted),
+// reporting:
+//
+//   - "unknown-field" errors for field and method references, like
+//     `.Page.Title`, which do not exist on the struct, pointer or map type
+//   - "range-type" errors for range pipelines which are not arrays, slices,
+//     maps, channels or integers
+//   - "arg-type" errors for FuncMap function arguments which are not
+//     assignable to the function parameter types
+//   - "block-structure" errors for mismatched control structures, in which
+//     case no other checks are made
+//
+// The dot is narrowed by with actions, range actions use the element type
+// and variables take the type of their declared value. Interface types, the
+// bodies of {{define}} actions and the results of builtin functions like
+// index and call are not known, so no checks are made through them. Unknown
+// functions and arity mismatches are reported by ValidateFuncs
+func TypeCheck(tree Tree, dataType reflect.Type, funcs map[string]any) (diagnostics Diagnostics) {
+	blocks, err := tree.Structure()
+	if err != nil {
+		d := Diagnostic{Severity: SeverityError, Code: "block-structure", Message: err.Error()}
+		var be *BlockError
+		if errors.As(err, &be) {
+			if be.Found != nil {
+				d.Pos = be.Found.Pos
+			} else if be.Opener != nil {
+				d.Pos = be.Opener.Pos
+			}
+		}
+		return Diagnostics{d}
+	}
+
+	tc := &typeChecker{funcs: make(map[string]reflect.Type)}
+	for name, fn := range funcs {
+		if ft := reflect.TypeOf(fn); ft != nil && ft.Kind() == reflect.Func && ft.NumOut() > 0 {
+			tc.funcs[name] = ft
+		}
+	}
+	tc.walk(blocks, &typeScope{dot: dataType, root: dataType})
+	tc.diagnostics.Sort()
+	return tc.diagnostics
+}
+
+// typeScope is the dot, `$` and variable types in scope while type checking,
+// a nil reflect.Type is an unknown type
+type typeScope struct {
+	dot  reflect.Type
+	root reflect.Type
+	vars map[string]reflect.Type
+}
+
+func (sc *typeScope) clone() (cloned *typeScope) {
+	cloned = &typeScope{dot: sc.dot, root: sc.root, vars: make(map[string]reflect.Type, len(sc.vars))}
+	for name, t := range sc.vars {
+		cloned.vars[name] = t
+	}
+	return
+}
+
+type typeChecker struct {
+	funcs       map[string]reflect.Type
+	diagnostics Diagnostics
+}
+
+func (tc *typeChecker) errorf(v *Variable, code, format string, argv ...any) {
+	tc.diagnostics = append(tc.diagnostics, Diagnostic{Pos: v.Pos, Severity: SeverityError, Code: code, Message: fmt.Sprintf(format, argv...)})
+}
+
+func (tc *typeChecker) walk(blocks BlockTree, sc *typeScope) {
+	for _, node := range blocks {
+		switch {
+		case node.Block != nil:
+			block := node.Block
+			switch block.Keyword {
+			case "define":
+				tc.walk(block.Body, &typeScope{})
+			case "block":
+				dot := tc.action(block.Open.Action, sc)
+				tc.walk(block.Body, &typeScope{dot: dot, root: dot})
+			default:
+				inner := sc.clone()
+				inner.dot = tc.action(block.Open.Action, inner)
+				tc.walk(block.Body, inner)
+				for _, clause := range block.Else {
+					inner.dot = sc.dot
+					if dot := tc.action(clause.Open.Action, inner); clause.Keyword == "else with" {
+						inner.dot = dot
+					}
+					tc.walk(clause.Body, inner)
+				}
+			}
+		case node.Branch != nil && node.Branch.Action != nil:
+			tc.action(node.Branch.Action, sc)
+		}
+	}
+}
+
+// action checks the given Action, updating any variables declared, and
+// returns the type of the dot within the body of with, range and block
+// actions (or the current dot for all other actions)
+func (tc *typeChecker) action(action *Action, sc *typeScope) (dot reflect.Type) {
+	dot = sc.dot
+	if len(action.Pipelines) == 0 {
+		return
+	}
+	s := action.Statement()
+	result := tc.commands(s.Commands, sc)
+
+	var key reflect.Type
+	switch s.Keyword {
+	case "with", "else with", "block":
+		dot = result
+	case "range":
+		var ok bool
+		if key, dot, ok = rangeTypes(result); !ok && len(s.Commands) > 0 && len(s.Commands[0]) > 0 {
+			tc.errorf(s.Commands[0][0], "range-type", "range can't iterate over %s", result)
+		}
+	}
+
+	if s.Declare != nil && !s.IsAssign {
+		if sc.vars == nil {
+			sc.vars = make(map[string]reflect.Type)
+		}
+		switch {
+		case s.Keyword == "range" && len(s.Decl) == 2:
+			sc.vars[s.Decl[0]], sc.vars[s.Decl[1]] = key, dot
+		case s.Keyword == "range" && len(s.Decl) == 1:
+			sc.vars[s.Decl[0]] = dot
+		default:
+			for _, name := range s.Decl {
+				sc.vars[name] = result
+			}
+		}
+	}
+	return
+}
+
+// commands checks the given pipe-separated commands and returns the type of
+// the final result
+func (tc *typeChecker) commands(commands []Variables, sc *typeScope) (result reflect.Type) {
+	for idx, command := range commands {
+		result = tc.command(command, idx > 0, result, sc)
+	}
+	return
+}
+
+// command checks the given command, with the piped result of the previous
+// command when piped is true, and returns the type of its result
+func (tc *typeChecker) command(command Variables, piped bool, previous reflect.Type, sc *typeScope) (result reflect.Type) {
+	if len(command) == 0 {
+		return nil
+	}
+	first := command[0]
+	if first.Ident == nil {
+		result = tc.operand(first, sc)
+		for _, v := range command[1:] {
+			tc.operand(v, sc)
+		}
+		return
+	}
+
+	name := *first.Ident
+	ft, isFunc := tc.funcs[name]
+	if !isFunc {
+		for _, v := range command[1:] {
+			tc.operand(v, sc)
+		}
+		return gBuiltinType[name]
+	}
+
+	for idx, v := range command[1:] {
+		tc.argument(name, idx, ft, v, tc.operand(v, sc))
+	}
+	if piped {
+		idx := len(command) - 1
+		if param := paramType(ft, idx); param != nil && previous != nil && !argAssignable(previous, param) {
+			tc.errorf(first, "arg-type", "wrong type for value; expected %s; got %s (piped into %s)", param, previous, name)
+		}
+	}
+	return ft.Out(0)
+}
+
+// argument checks the given argument Variable, with the given type (nil when
+// not known), against the parameter type of argument idx of ft
+func (tc *typeChecker) argument(name string, idx int, ft reflect.Type, v *Variable, t reflect.Type) {
+	param := paramType(ft, idx)
+	if param == nil {
+		return
+	}
+	var ok bool
+	var got string
+	switch {
+	case v.Nil != nil:
+		switch param.Kind() {
+		case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Pointer, reflect.Slice:
+			ok = true
+		}
+		got = "nil"
+	case v.String != nil || v.Literal != nil:
+		ok, got = param.Kind() == reflect.String || param.Kind() == reflect.Interface, "string constant"
+	case v.Bool != nil:
+		ok, got = param.Kind() == reflect.Bool || param.Kind() == reflect.Interface, "bool constant"
+	case v.Int != nil || v.Float != nil || v.Rune != nil || v.Number != nil:
+		switch param.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+			reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128, reflect.Interface:
+			ok = true
+		}
+		got = "number constant"
+	case t == nil:
+		return
+	default:
+		ok, got = argAssignable(t, param), t.String()
+	}
+	if !ok {
+		tc.errorf(v, "arg-type", "wrong type for value; expected %s; got %s (argument %d of %s)", param, got, idx+1, name)
+	}
+}
+
+// operand checks the given operand Variable and returns its type
+func (tc *typeChecker) operand(v *Variable, sc *typeScope) (t reflect.Type) {
+	switch {
+	case v.Dot != nil:
+		return sc.dot
+	case v.String != nil || v.Literal != nil:
+		return gStringType
+	case v.Int != nil:
+		return gIntType
+	case v.Float != nil:
+		return gFloatType
+	case v.Rune != nil:
+		return gRuneType
+	case v.Bool != nil:
+		return gBoolType
+	case v.Keyword != nil:
+		var path string
+		if strings.HasPrefix(*v.Keyword, "$") {
+			name := rxVariableName.FindString(*v.Keyword)
+			if name == "" {
+				t, path = sc.root, (*v.Keyword)[1:]
+			} else {
+				t, path = sc.vars[name], (*v.Keyword)[len(name):]
+			}
+		} else {
+			t, path = sc.dot, *v.Keyword
+		}
+		return tc.fields(v, t, path)
+	case v.Grouping != nil && v.Grouping.Group != nil:
+		t = tc.commands(v.Grouping.Statement().Commands, sc)
+		if v.Grouping.Field != nil {
+			t = tc.fields(v, t, *v.Grouping.Field)
+		}
+		return
+	}
+	return nil
+}
+
+// fields returns the type of the given `.Field.Other` path on the given type,
+// reporting the first field which does not exist
+func (tc *typeChecker) fields(v *Variable, t reflect.Type, path string) reflect.Type {
+	for _, name := range strings.Split(path, ".") {
+		if name == "" {
+			continue
+		} else if t == nil {
+			return nil
+		}
+		var problem string
+		if t, problem = fieldType(t, name); problem != "" {
+			tc.errorf(v, "unknown-field", "%s", problem)
+			return nil
+		}
+	}
+	return t
+}
+
+// fieldType returns the type of the named field or method of t, nil when the
+// type is not known, or a description of why the field does not exist
+func fieldType(t reflect.Type, name string) (field reflect.Type, problem string) {
+	receiver := t
+	for {
+		if m, ok := t.MethodByName(name); ok {
+			return methodResult(m.Type), ""
+		} else if t.Kind() != reflect.Pointer && t.Kind() != reflect.Interface {
+			if m, ok := reflect.PointerTo(t).MethodByName(name); ok {
+				return methodResult(m.Type), ""
+			}
+		}
+		if t.Kind() != reflect.Pointer {
+			break
+		}
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return nil, ""
+	case reflect.Struct:
+		if f, ok := t.FieldByName(name); !ok {
+			return nil, fmt.Sprintf("can't evaluate field %s in type %s", name, receiver)
+		} else if !f.IsExported() {
+			return nil, fmt.Sprintf("%s is an unexported field of struct type %s", name, receiver)
+		} else {
+			return f.Type, ""
+		}
+	case reflect.Map:
+		if t.Key().Kind() == reflect.String {
+			return t.Elem(), ""
+		}
+	}
+	return nil, fmt.Sprintf("can't evaluate field %s in type %s", name, receiver)
+}
+
+// methodResult returns the first result type of the given method type
+func methodResult(mt reflect.Type) reflect.Type {
+	if mt.NumOut() > 0 {
+		return mt.Out(0)
+	}
+	return nil
+}
+
+// rangeTypes returns the key and element types of ranging over t, nil when
+// not known, and false when t cannot be iterated
+func rangeTypes(t reflect.Type) (key, elem reflect.Type, ok bool) {
+	if t == nil {
+		return nil, nil, true
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Array, reflect.Slice:
+		return gIntType, t.Elem(), true
+	case reflect.Map:
+		return t.Key(), t.Elem(), true
+	case reflect.Chan:
+		return nil, t.Elem(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return t, t, true
+	case reflect.Interface, reflect.Func:
+		return nil, nil, true
+	}
+	return nil, nil, false
+}
+
+// paramType returns the type of argument idx of the function type ft, or
+// nil when there is no such argument
+func paramType(ft reflect.Type, idx int) reflect.Type {
+	switch {
+	case ft.IsVariadic() && idx >= ft.NumIn()-1:
+		return ft.In(ft.NumIn() - 1).Elem()
+	case idx < ft.NumIn():
+		return ft.In(idx)
+	}
+	return nil
+}
+
+// argAssignable reports whether a value of type t can be passed as an
+// argument of type param, including the pointer indirection text/template
+// performs
+func argAssignable(t, param reflect.Type) bool {
+	switch {
+	case t.AssignableTo(param):
+		return true
+	case t.Kind() == reflect.Interface:
+		return true
+	case t.Kind() == reflect.Pointer && t.Elem().AssignableTo(param):
+		return true
+	case param.Kind() == reflect.Pointer && t.AssignableTo(param.Elem()):
+		return true
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2024  The Go-CoreLibs Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tmplstr
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type tcAuthor struct {
+	Name  string
+	email string
+}
+
+type tcItem struct {
+	Name  string
+	Price float64
+	Tags  map[string]string
+}
+
+func (i tcItem) Label() string { return i.Name }
+
+func (i *tcItem) Discounted(pct int) float64 { return i.Price }
+
+type tcPage struct {
+	Title   string
+	Author  *tcAuthor
+	Items   []*tcItem
+	Counts  map[string]int
+	Extra   any
+	Created time.Time
+	Draft   bool
+}
+
+func TestTypeCheck(t *testing.T) {
+
+	funcs := template.FuncMap{
+		"upper":  strings.ToUpper,
+		"repeat": strings.Repeat,
+		"money":  func(v float64) string { return "" },
+		"join":   func(sep string, values ...string) string { return "" },
+	}
+
+	check := func(input string) (found []string) {
+		tree, err := ParseTemplate("check.tmpl", input)
+		So(err, ShouldBeNil)
+		for _, d := range TypeCheck(tree, reflect.TypeOf(tcPage{}), funcs) {
+			found = append(found, d.String())
+		}
+		return
+	}
+
+	Convey("valid templates", t, func() {
+		So(check(`{{ .Title }}{{ .Author.Name }}{{ with .Author }}{{ .Name }}{{ end }}`+
+			`{{ range .Items }}{{ .Name }}{{ .Label }}{{ .Discounted 10 }}{{ .Tags.any }}{{ $.Title }}{{ end }}`+
+			`{{ range $k, $v := .Counts }}{{ $k | upper }}{{ $v }}{{ end }}`+
+			`{{ .Extra.Anything.Goes }}{{ .Created.Year }}{{ (index .Items 0).Missing }}`+
+			`{{ $a := .Author }}{{ $a.Name }}{{ .Title | upper | printf "%s" }}`+
+			`{{ repeat .Title 3 }}{{ range $i, $e := .Items }}{{ money $e.Price }}{{ end }}`+
+			`{{ join "," "a" .Title }}{{ .Items | len }}{{ define "x" }}{{ .Whatever }}{{ end }}`), ShouldBeEmpty)
+	})
+
+	Convey("unknown fields", t, func() {
+		So(check(`{{ .Titel }}{{ .Author.Nmae }}{{ .Author.email }}{{ with .Author }}{{ .Title }}{{ end }}`+
+			`{{ range .Items }}{{ .Author }}{{ end }}{{ $.Title.Length }}{{ (.Author).Age }}`), ShouldEqual, []string{
+			"check.tmpl:1:4: error: can't evaluate field Titel in type tmplstr.tcPage (unknown-field)",
+			"check.tmpl:1:16: error: can't evaluate field Nmae in type *tmplstr.tcAuthor (unknown-field)",
+			"check.tmpl:1:34: error: email is an unexported field of struct type *tmplstr.tcAuthor (unknown-field)",
+			"check.tmpl:1:71: error: can't evaluate field Title in type *tmplstr.tcAuthor (unknown-field)",
+			"check.tmpl:1:110: error: can't evaluate field Author in type *tmplstr.tcItem (unknown-field)",
+			"check.tmpl:1:132: error: can't evaluate field Length in type string (unknown-field)",
+			"check.tmpl:1:152: error: can't evaluate field Age in type *tmplstr.tcAuthor (unknown-field)",
+		})
+	})
+
+	Convey("range and argument types", t, func() {
+		So(check(`{{ range .Title }}{{ end }}{{ range .Draft }}{{ end }}{{ money .Title }}{{ upper 1 }}{{ .Draft | upper }}{{ repeat "x" "y" }}{{ join "," .Draft }}{{ money nil }}`), ShouldEqual, []string{
+			"check.tmpl:1:10: error: range can't iterate over string (range-type)",
+			"check.tmpl:1:37: error: range can't iterate over bool (range-type)",
+			"check.tmpl:1:64: error: wrong type for value; expected float64; got string (argument 1 of money) (arg-type)",
+			"check.tmpl:1:82: error: wrong type for value; expected string; got number constant (argument 1 of upper) (arg-type)",
+			"check.tmpl:1:98: error: wrong type for value; expected string; got bool (piped into upper) (arg-type)",
+			"check.tmpl:1:120: error: wrong type for value; expected int; got string constant (argument 2 of repeat) (arg-type)",
+			"check.tmpl:1:138: error: wrong type for value; expected string; got bool (argument 2 of join) (arg-type)",
+			"check.tmpl:1:156: error: wrong type for value; expected float64; got nil (argument 1 of money) (arg-type)",
+		})
+	})
+
+	Convey("text/template parity", t, func() {
+		data := tcPage{Title: "t", Author: &tcAuthor{Name: "a"}, Items: []*tcItem{{Name: "i"}}}
+		for _, input := range []string{
+			`{{ .Titel }}`,
+			`{{ .Author.Nmae }}`,
+			`{{ range .Items }}{{ .Author }}{{ end }}`,
+			`{{ range .Title }}{{ end }}`,
+			`{{ money .Title }}`,
+			`{{ .Author.Name }}{{ range .Items }}{{ .Label }}{{ end }}`,
+		} {
+			Convey(input, func() {
+				tmpl, err := template.New("parity").Funcs(funcs).Parse(input)
+				So(err, ShouldBeNil)
+				execErr := tmpl.Execute(&strings.Builder{}, data)
+				So(check(input) != nil, ShouldEqual, execErr != nil)
+			})
+		}
+	})
+
+	Convey("mismatched structures", t, func() {
+		So(check(`{{ range .Items }}`), ShouldHaveLength, 1)
+		So(check(`{{ end }}`)[0], ShouldStartWith, "check.tmpl:1:1: error: unexpected {{end}}")
+	})
+}
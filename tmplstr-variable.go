@@ -18,21 +18,49 @@ import (
 	"fmt"
 	"strconv"
 	"unicode/utf8"
+
+	"github.com/alecthomas/participle/v2/lexer"
 )
 
+// Variable is a single token within a Pipeline, which is one of: assignment,
+// range declaration, boolean, nil, identifier, keyword, dot, literal, string,
+// rune, number, float, int, whitespace, comment or a Grouping
+//
+// Keyword is used for both variables (`$`, `$name`, `$name.Field`) and field
+// chains (`.Field.Other`) while the lone dot (`.`) is stored in Dot. Plain
+// decimal numbers are parsed into Int or Float while all other numeric
+// spellings (signed, hexadecimal, octal, binary, exponents, imaginary and
+// underscore separated) are kept as their original source text in Number
 type Variable struct {
-	Assign   *string   `parser:"(  @Assignment" json:"assign,omitempty"`
-	Range    *string   `parser:" | @Range"      json:"range,omitempty"`
-	Ident    *string   `parser:" | @Ident"      json:"ident,omitempty"`
-	Keyword  *string   `parser:" | @Keyword"    json:"keyword,omitempty"`
-	Literal  *string   `parser:" | @Literal"    json:"literal,omitempty"`
-	String   *string   `parser:" | @String"     json:"string,omitempty"`
-	Rune     *string   `parser:" | @Rune"       json:"rune,omitempty"`
-	Float    *float64  `parser:" | @Float"      json:"float,omitempty"`
-	Int      *int      `parser:" | @Int"        json:"int,omitempty"`
-	Space    *string   `parser:" | ( @Space )+" json:"space,omitempty"`
-	Comment  *string   `parser:" | @Comment"    json:"comment,omitempty"`
-	Grouping *Grouping `parser:" | @@ )"        json:"grouping,omitempty"`
+	Assign   *string   `parser:"(  @Assignment"            json:"assign,omitempty"`
+	Range    *string   `parser:" | @Range"                 json:"range,omitempty"`
+	Bool     *Boolean  `parser:" | @Bool"                  json:"bool,omitempty"`
+	Nil      *string   `parser:" | @Nil"                   json:"nil,omitempty"`
+	Ident    *string   `parser:" | @Ident"                 json:"ident,omitempty"`
+	Keyword  *string   `parser:" | @( Keyword | Field )"   json:"keyword,omitempty"`
+	Dot      *string   `parser:" | @Dot"                   json:"dot,omitempty"`
+	Literal  *string   `parser:" | @Literal"               json:"literal,omitempty"`
+	String   *string   `parser:" | @String"                json:"string,omitempty"`
+	Rune     *string   `parser:" | @Rune"                  json:"rune,omitempty"`
+	Number   *string   `parser:" | @Number"                json:"number,omitempty"`
+	Float    *float64  `parser:" | @Float"                 json:"float,omitempty"`
+	Int      *int      `parser:" | @Int"                   json:"int,omitempty"`
+	Space    *string   `parser:" | ( @Space )+"            json:"space,omitempty"`
+	Comment  *string   `parser:" | @Comment"               json:"comment,omitempty"`
+	Grouping *Grouping `parser:" | @@ )"                   json:"grouping,omitempty"`
+
+	// Raw is the original source text of String, Literal, Rune, Float and Int
+	// Variables. Render uses Raw instead of re-encoding the value, unless the
+	// value has been modified and no longer matches Raw
+	Raw string `parser:"" json:"raw,omitempty"`
+
+	// ActionComment is true when this Variable is a Comment which is the only
+	// content of its Action, like `{{/* comment */}}`, and false for inline
+	// comments like the one in `{{ _ "key" /* comment */ }}`
+	ActionComment bool `parser:"" json:"action_comment,omitempty"`
+
+	Pos    lexer.Position `parser:"" json:"-"`
+	EndPos lexer.Position `parser:"" json:"-"`
 }
 
 // Render returns the source text represented by this Variable
@@ -42,16 +70,39 @@ func (v *Variable) Render() (source string) {
 		return *v.Ident
 	case v.Keyword != nil:
 		return *v.Keyword
+	case v.Dot != nil:
+		return *v.Dot
+	case v.Bool != nil:
+		return strconv.FormatBool(bool(*v.Bool))
+	case v.Nil != nil:
+		return *v.Nil
+	case v.Number != nil:
+		return *v.Number
 	case v.Literal != nil:
+		if v.Raw == "`"+*v.Literal+"`" {
+			return v.Raw
+		}
 		return "`" + *v.Literal + "`"
 	case v.String != nil:
+		if value, err := strconv.Unquote(v.Raw); err == nil && value == *v.String {
+			return v.Raw
+		}
 		return strconv.Quote(*v.String)
 	case v.Rune != nil:
+		if value, err := strconv.Unquote(v.Raw); err == nil && value == *v.Rune {
+			return v.Raw
+		}
 		r, _ := utf8.DecodeRuneInString(*v.Rune)
 		return strconv.QuoteRune(r)
 	case v.Float != nil:
+		if value, err := strconv.ParseFloat(v.Raw, 64); err == nil && value == *v.Float {
+			return v.Raw
+		}
 		return fmt.Sprintf("%v", *v.Float)
 	case v.Int != nil:
+		if value, err := strconv.ParseInt(v.Raw, 0, 64); err == nil && int(value) == *v.Int {
+			return v.Raw
+		}
 		return strconv.Itoa(*v.Int)
 	case v.Space != nil:
 		return *v.Space
@@ -66,3 +117,21 @@ func (v *Variable) Render() (source string) {
 	}
 	return
 }
+
+// offset updates the positions of this Variable, and any child nodes, to be
+// relative to the given base position
+func (v *Variable) offset(base lexer.Position) {
+	v.Pos, v.EndPos = base.Add(v.Pos), base.Add(v.EndPos)
+	if v.Grouping != nil {
+		v.Grouping.offset(base)
+	}
+}
+
+// Boolean is a participle capture type for the `true` and `false` literals
+type Boolean bool
+
+// Capture implements the participle.Capture interface
+func (b *Boolean) Capture(values []string) error {
+	*b = len(values) > 0 && values[0] == "true"
+	return nil
+}
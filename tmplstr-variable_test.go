@@ -16,6 +16,7 @@ package tmplstr
 
 import (
 	"testing"
+	"text/template/parse"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -25,4 +26,145 @@ func TestVariable(t *testing.T) {
 		v := &Variable{}
 		So(v.Render(), ShouldEqual, "")
 	})
+
+	Convey("text/template grammar", t, func() {
+		corpus := []string{
+			`{{.}}`,
+			`{{ . }}`,
+			`{{$}}`,
+			`{{ $.Site.Name }}`,
+			`{{ $x := . }}{{ $x.Field.Other }}`,
+			`{{$x:=1}}{{$x = 2}}`,
+			`{{ eq .x nil }}`,
+			`{{ true }} {{ false }}`,
+			`{{ -3 }} {{-3}} {{ +3 }} {{- -3 -}}`,
+			`{{ 0x1F }} {{ 0X1f }} {{ 0o17 }} {{ 0b101 }} {{ 017 }}`,
+			`{{ 1e3 }} {{ 1.5e-3 }} {{ 2i }} {{ 1_000 }} {{ .5 }} {{ 0x1p-2 }}`,
+			`{{ (index .x 0).Name }}`,
+			`{{ (.x).Field.Other }}`,
+			`{{ range $i, $e := .x }}{{ end }}`,
+			`{{ range $e := .x }}{{ end }}`,
+			`{{ "a\b" }} {{ "" }} {{ ` + "``" + ` }}`,
+			`{{ '\'' }} {{ 'é' }}`,
+			`{{ .Ünicode }} {{ ._private }} {{ $_x := 1 }}`,
+			`{{ printf "%d" (len .x) | print }}`,
+			"{{ `multi\nline` }}",
+		}
+		for _, input := range corpus {
+			Convey(input, func() {
+				std := parse.New("corpus")
+				std.Mode = parse.SkipFuncCheck
+				_, err := std.Parse(input, "", "", map[string]*parse.Tree{})
+				So(err, ShouldBeNil)
+				tree, err := ParseTemplate("corpus.tmpl", input)
+				So(err, ShouldBeNil)
+				So(tree.Render(), ShouldEqual, input)
+			})
+		}
+	})
+
+	Convey("lossless literals", t, func() {
+		for _, input := range []string{
+			`{{ 1.50 }}`,
+			`{{ 1.0 }}`,
+			`{{ "\x41" }}`,
+			`{{ "\u00e9\t" }}`,
+			`{{ '\x41' }}`,
+			`{{ '\u00e9' }}`,
+			`{{ 'é' }}`,
+			"{{ `raw \\n text` }}",
+			`{{ 0755 }}`,
+			`{{ (print "\x41" 1.50) }}`,
+		} {
+			tree, err := ParseTemplate("lossless.tmpl", input)
+			So(err, ShouldBeNil)
+			So(tree.Render(), ShouldEqual, input)
+		}
+
+		tree, err := ParseTemplate("lossless.tmpl", "{{ \"\\x41\" 1.50 '\\x41' 10 `a\\nb` }}")
+		So(err, ShouldBeNil)
+		root := tree[0].Action.Pipelines[0].Root
+		So(*root[1].String, ShouldEqual, "A")
+		So(root[1].Raw, ShouldEqual, `"\x41"`)
+		So(*root[3].Float, ShouldEqual, 1.5)
+		So(root[3].Raw, ShouldEqual, `1.50`)
+		So(*root[5].Rune, ShouldEqual, "A")
+		So(root[5].Raw, ShouldEqual, `'\x41'`)
+		So(root[7].Raw, ShouldEqual, `10`)
+		So(*root[9].Literal, ShouldEqual, `a\nb`)
+		So(root[9].Raw, ShouldEqual, "`a\\nb`")
+
+		Convey("modified values", func() {
+			*root[1].String = "B"
+			*root[3].Float = 2.5
+			*root[5].Rune = "é"
+			*root[7].Int = 11
+			*root[9].Literal = "c"
+			So(tree.Render(), ShouldEqual, "{{ \"B\" 2.5 'é' 11 `c` }}")
+		})
+	})
+
+	Convey("literal and operand kinds", t, func() {
+		kinds := func(input string) (found []*Variable) {
+			tree, err := ParseTemplate("kinds.tmpl", input)
+			So(err, ShouldBeNil)
+			tree.WalkVariables(func(variables *Variables) (stop bool) {
+				for _, v := range *variables {
+					if v.Space == nil && v.Grouping == nil {
+						found = append(found, v)
+					}
+				}
+				return
+			})
+			return
+		}
+
+		found := kinds(`{{ . $ true false nil }}`)
+		So(found, ShouldHaveLength, 5)
+		So(*found[0].Dot, ShouldEqual, ".")
+		So(*found[1].Keyword, ShouldEqual, "$")
+		So(bool(*found[2].Bool), ShouldBeTrue)
+		So(bool(*found[3].Bool), ShouldBeFalse)
+		So(*found[4].Nil, ShouldEqual, "nil")
+
+		found = kinds(`{{ 10 1.5 -3 0x1F 1e3 2i 1_000 }}`)
+		So(found, ShouldHaveLength, 7)
+		So(*found[0].Int, ShouldEqual, 10)
+		So(*found[1].Float, ShouldEqual, 1.5)
+		for idx, number := range []string{"-3", "0x1F", "1e3", "2i", "1_000"} {
+			So(found[idx+2].Number, ShouldNotBeNil)
+			So(*found[idx+2].Number, ShouldEqual, number)
+		}
+
+		tree, err := ParseTemplate("kinds.tmpl", `{{-3}}`)
+		So(err, ShouldBeNil)
+		So(*tree[0].Action.Open, ShouldEqual, "{{")
+		v := tree[0].Action.Pipelines[0].Root[0]
+		So(*v.Number, ShouldEqual, "-3")
+		So(v.Pos.Offset, ShouldEqual, 2)
+		So(v.EndPos.Offset, ShouldEqual, 4)
+
+		tree, err = ParseTemplate("kinds.tmpl", `{{- 3}}`)
+		So(err, ShouldBeNil)
+		So(*tree[0].Action.Open, ShouldEqual, "{{-")
+
+		tree, err = ParseTemplate("kinds.tmpl", `{{ (index .x 0).Name.Other }}`)
+		So(err, ShouldBeNil)
+		grouping := tree[0].Action.Pipelines[0].Root[1].Grouping
+		So(grouping, ShouldNotBeNil)
+		So(*grouping.Field, ShouldEqual, ".Name.Other")
+		So(tree.Render(), ShouldEqual, `{{ (index .x 0).Name.Other }}`)
+
+		// integers too large for an int are kept as Number values
+		found = kinds(`{{ 9223372036854775807 18446744073709551615 99999999999999999999 }}`)
+		So(found, ShouldHaveLength, 3)
+		So(*found[0].Int, ShouldEqual, 9223372036854775807)
+		So(found[1].Int, ShouldBeNil)
+		So(*found[1].Number, ShouldEqual, "18446744073709551615")
+		So(*found[2].Number, ShouldEqual, "99999999999999999999")
+
+		found = kinds(`{{ $x.Field .Field.Other }}`)
+		So(*found[0].Keyword, ShouldEqual, "$x.Field")
+		So(*found[1].Keyword, ShouldEqual, ".Field.Other")
+	})
 }
@@ -16,30 +16,103 @@
 package tmplstr
 
 import (
-	clStrings "github.com/go-corelibs/strings"
+	"github.com/alecthomas/participle/v2/lexer"
 )
 
 // ParseTemplate uses [github.com/alecthomas/participle/v2] to parse the given
 // template file content into an abstract syntax tree. ParseTemplate is
 // intended to facilitate extracting contextual information from text or html
 // template source text
+//
+// Every node within the returned Tree has its Pos and EndPos fields set to the
+// start and end positions of the node within the given input, including the
+// byte offset along with the line and column numbers
+//
+// When an action fails to parse, the error returned is a *ParseError
+//
+// ParseTemplate uses the standard `{{` and `}}` action delimiters, see
+// NewParser and WithDelims for using custom delimiters
 func ParseTemplate(filename, input string) (trees Tree, err error) {
+	return gDefaultParser.ParseTemplate(filename, input)
+}
+
+// ParseTemplateTolerant is like ParseTemplate except that actions which fail
+// to parse do not stop the parsing process. Each failed action is recorded as
+// a Branch with the Invalid field set and parsing continues with the rest of
+// the input. When any actions failed to parse, the error returned is a
+// ParseErrors list of all the failures, in the order they were found
+func ParseTemplateTolerant(filename, input string) (trees Tree, err error) {
+	return gDefaultParser.ParseTemplateTolerant(filename, input)
+}
+
+// ParseTemplate is the Parser equivalent of the package-level ParseTemplate
+// function, using this Parser's action delimiters
+func (p *Parser) ParseTemplate(filename, input string) (trees Tree, err error) {
+	var errs ParseErrors
+	if trees, errs = p.parseTemplate(filename, input, false); len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return
+}
+
+// ParseTemplateTolerant is the Parser equivalent of the package-level
+// ParseTemplateTolerant function, using this Parser's action delimiters
+func (p *Parser) ParseTemplateTolerant(filename, input string) (trees Tree, err error) {
+	var errs ParseErrors
+	if trees, errs = p.parseTemplate(filename, input, true); len(errs) > 0 {
+		err = errs
+	}
+	return
+}
+
+func (p *Parser) parseTemplate(filename, input string, tolerant bool) (trees Tree, errs ParseErrors) {
+	pos := lexer.Position{Filename: filename, Line: 1, Column: 1}
 	for tmp := input[:]; len(tmp) > 0; {
-		if before, text, after, found := clStrings.ScanCarve(tmp, "{{", "}}"); found {
+		before, text, after, found, scanErr := scanAction(tmp, p.left, p.right)
+		if found || scanErr != nil {
 			if len(before) > 0 {
 				// keep stuff before text
-				trees = append(trees, &Branch{Text: &before})
+				branch := &Branch{Text: &before, Pos: pos}
+				pos.Advance(before)
+				branch.EndPos = pos
+				trees = append(trees, branch)
 			}
 			tmp = after // setup next iteration
-			var stmnt *Action
-			if stmnt, err = gTemplateParser.ParseString(filename, "{{"+text+"}}"); err != nil {
-				return nil, err
+			source := p.left + text
+			if found {
+				source += p.right
+			}
+			branch := &Branch{Pos: pos}
+			if scanErr != nil {
+				// unterminated comment or quotation, the rest of the input
+				pe := p.newParseError(input, source, pos, scanErr)
+				if errs = append(errs, pe); !tolerant {
+					return
+				}
+				branch.Invalid = &Invalid{Source: source, Err: pe}
+			} else if stmnt, err := p.parser.ParseString(filename, source); err != nil {
+				pe := p.newParseError(input, source, pos, err)
+				if errs = append(errs, pe); !tolerant {
+					return
+				}
+				branch.Invalid = &Invalid{Source: source, Err: pe}
+			} else {
+				stmnt.fixSignedNumber(source)
+				stmnt.markActionComment()
+				stmnt.captureRaw(source)
+				stmnt.offset(pos)
+				branch.Action = stmnt
 			}
-			trees = append(trees, &Branch{Action: stmnt})
+			pos.Advance(source)
+			branch.EndPos = pos
+			trees = append(trees, branch)
 			continue // move to next iteration
 		}
 		// keep remainder
-		trees = append(trees, &Branch{Text: &tmp})
+		branch := &Branch{Text: &tmp, Pos: pos}
+		pos.Advance(tmp)
+		branch.EndPos = pos
+		trees = append(trees, branch)
 		break
 	}
 	return
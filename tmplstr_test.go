@@ -15,9 +15,12 @@
 package tmplstr
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
+	"github.com/alecthomas/participle/v2/lexer"
+
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -45,6 +48,36 @@ func mkInt(input int) *int {
 	return &input
 }
 
+// tStripTrivia returns the given tree with all node positions and raw literal
+// source text cleared
+func tStripTrivia(tree Tree) Tree {
+	var stripPipeline func(p *Pipeline)
+	stripPipeline = func(p *Pipeline) {
+		if p == nil {
+			return
+		}
+		p.Pos, p.EndPos = lexer.Position{}, lexer.Position{}
+		for _, v := range p.Root {
+			v.Pos, v.EndPos, v.Raw = lexer.Position{}, lexer.Position{}, ""
+			if v.Grouping != nil {
+				v.Grouping.Pos, v.Grouping.EndPos = lexer.Position{}, lexer.Position{}
+				stripPipeline(v.Grouping.Group)
+			}
+		}
+		stripPipeline(p.Pipe)
+	}
+	for _, branch := range tree {
+		branch.Pos, branch.EndPos = lexer.Position{}, lexer.Position{}
+		if branch.Action != nil {
+			branch.Action.Pos, branch.Action.EndPos = lexer.Position{}, lexer.Position{}
+			for _, pipeline := range branch.Action.Pipelines {
+				stripPipeline(pipeline)
+			}
+		}
+	}
+	return tree
+}
+
 func TestTmplStr(t *testing.T) {
 
 	cases := []struct {
@@ -120,7 +153,7 @@ func TestTmplStr(t *testing.T) {
 				{Action: &Action{
 					Open: mkStr("{{"),
 					Pipelines: Pipelines{{Root: Variables{
-						{Comment: mkStr("/* a comment */")},
+						{Comment: mkStr("/* a comment */"), ActionComment: true},
 					}}},
 					Close: mkStr("}}"),
 				}},
@@ -1083,11 +1116,103 @@ func TestTmplStr(t *testing.T) {
 					So(err, ShouldBeNil)
 					So(trees.Render(), ShouldEqual, test.input)
 				}
-				So(trees, ShouldEqual, test.trees)
+				So(tStripTrivia(trees), ShouldEqual, test.trees)
+			})
+		}
+	})
+
+	Convey("Format idempotency", t, func() {
+		for idx, test := range cases {
+			if test.err {
+				continue
+			}
+			filename := fmt.Sprintf("testing.%d.tmpl", idx+1)
+			Convey(filename+" ("+test.label+")", func() {
+				for _, opts := range []FormatOptions{{}, {NestPadding: "\t"}, {Compact: true}} {
+					tFormatStable(filename, test.input, opts)
+				}
 			})
 		}
 	})
 
+	Convey("ParseTemplate positions", t, func() {
+		input := "one\n  {{ if .x }}é{{ _ \"a\" (b .c) }}"
+		tree, err := ParseTemplate("positions.tmpl", input)
+		So(err, ShouldBeNil)
+		So(tree, ShouldHaveLength, 4)
+
+		So(tree[0].Pos, ShouldEqual, lexer.Position{Filename: "positions.tmpl", Offset: 0, Line: 1, Column: 1})
+		So(tree[0].EndPos, ShouldEqual, lexer.Position{Filename: "positions.tmpl", Offset: 6, Line: 2, Column: 3})
+
+		So(tree[1].Pos, ShouldEqual, lexer.Position{Filename: "positions.tmpl", Offset: 6, Line: 2, Column: 3})
+		So(tree[1].EndPos, ShouldEqual, lexer.Position{Filename: "positions.tmpl", Offset: 17, Line: 2, Column: 14})
+		So(tree[1].Action.Pos, ShouldEqual, tree[1].Pos)
+		So(tree[1].Action.EndPos, ShouldEqual, tree[1].EndPos)
+		keyword := tree[1].Action.Pipelines[0].Root[3]
+		So(*keyword.Keyword, ShouldEqual, ".x")
+		So(keyword.Pos, ShouldEqual, lexer.Position{Filename: "positions.tmpl", Offset: 12, Line: 2, Column: 9})
+		So(keyword.EndPos, ShouldEqual, lexer.Position{Filename: "positions.tmpl", Offset: 14, Line: 2, Column: 11})
+		So(input[keyword.Pos.Offset:keyword.EndPos.Offset], ShouldEqual, ".x")
+
+		So(tree[2].Pos, ShouldEqual, lexer.Position{Filename: "positions.tmpl", Offset: 17, Line: 2, Column: 14})
+		So(tree[2].EndPos, ShouldEqual, lexer.Position{Filename: "positions.tmpl", Offset: 19, Line: 2, Column: 15})
+
+		grouping := tree[3].Action.Pipelines[0].Root[5].Grouping
+		So(grouping, ShouldNotBeNil)
+		So(grouping.Pos, ShouldEqual, lexer.Position{Filename: "positions.tmpl", Offset: 28, Line: 2, Column: 24})
+		So(input[grouping.Pos.Offset:grouping.EndPos.Offset], ShouldEqual, "(b .c)")
+		inner := grouping.Group.Root[2]
+		So(inner.Pos, ShouldEqual, lexer.Position{Filename: "positions.tmpl", Offset: 31, Line: 2, Column: 27})
+		So(input[inner.Pos.Offset:inner.EndPos.Offset], ShouldEqual, ".c")
+		So(tree[3].EndPos.Offset, ShouldEqual, len(input))
+	})
+
+	Convey("ParseTemplateTolerant", t, func() {
+
+		Convey("no errors", func() {
+			tree, err := ParseTemplateTolerant("tolerant.tmpl", `a {{ b }} c`)
+			So(err, ShouldBeNil)
+			So(tree, ShouldHaveLength, 3)
+		})
+
+		Convey("continues after invalid actions", func() {
+			input := "before {{ [ }} middle {{ good }}\n{{ ) }} after"
+			tree, err := ParseTemplateTolerant("tolerant.tmpl", input)
+			So(err, ShouldNotBeNil)
+			errs, ok := err.(ParseErrors)
+			So(ok, ShouldBeTrue)
+			So(errs, ShouldHaveLength, 2)
+			So(errs[0].Pos.Offset, ShouldEqual, 10)
+			So(errs[1].Pos.Line, ShouldEqual, 2)
+			So(errs[1].Pos.Column, ShouldEqual, 4)
+			So(err.Error(), ShouldEqual, errs[0].Error()+"\n"+errs[1].Error())
+			var pe *ParseError
+			So(errors.As(err, &pe), ShouldBeTrue)
+			So(pe, ShouldEqual, errs[0])
+
+			So(tree, ShouldHaveLength, 7)
+			So(tree[1].Invalid, ShouldNotBeNil)
+			So(tree[1].Invalid.Source, ShouldEqual, "{{ [ }}")
+			So(tree[1].Invalid.Err, ShouldEqual, errs[0])
+			So(tree[1].Pos.Offset, ShouldEqual, 7)
+			So(tree[1].EndPos.Offset, ShouldEqual, 14)
+			So(tree[3].Action, ShouldNotBeNil)
+			So(tree[5].Invalid.Source, ShouldEqual, "{{ ) }}")
+			So(tree.Render(), ShouldEqual, input)
+
+			var idents []string
+			tree.WalkVariables(func(variables *Variables) (stop bool) {
+				for _, v := range *variables {
+					if v.Ident != nil {
+						idents = append(idents, *v.Ident)
+					}
+				}
+				return
+			})
+			So(idents, ShouldEqual, []string{"good"})
+		})
+	})
+
 	Convey("Branch.Render", t, func() {
 		c := &Branch{}
 		So(c.Render(), ShouldEqual, "")